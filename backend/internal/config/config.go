@@ -1,12 +1,20 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// ProviderConfig names an extra Amazon Music compatible search storefront.
+type ProviderConfig struct {
+	Name    string
+	BaseURL string
+}
+
 // Config holds runtime configuration loaded from environment variables.
 type Config struct {
 	Port             string
@@ -17,10 +25,79 @@ type Config struct {
 	EnableDownloads  bool
 	DownloadTimeout  time.Duration
 	AmazonAPIBaseURL string
+	SearchCacheTTL   time.Duration
+	// SearchProviders are queried after Amazon, in priority order.
+	SearchProviders       []ProviderConfig
+	SearchProviderTimeout time.Duration
+
+	// ResolverBaseURL points at the doubledouble.top style link resolver.
+	ResolverBaseURL string
+	// Upstream* tune the shared outbound HTTP layer (per host).
+	UpstreamRate             float64
+	UpstreamBurst            int
+	UpstreamMaxRetries       int
+	UpstreamBreakerThreshold int
+	UpstreamBreakerCooldown  time.Duration
+
+	// NetworkConcurrency caps jobs fetching/downloading at once;
+	// DiskConcurrency caps jobs extracting/placing at once.
+	NetworkConcurrency int
+	DiskConcurrency    int
+
+	// JobMaxAttempts bounds automatic retries of failed jobs (1 disables them);
+	// JobRetryBackoff is the delay before the first retry, doubled after each.
+	JobMaxAttempts  int
+	JobRetryBackoff time.Duration
+
+	// QueueMaxDepth limits queued jobs; imports beyond it are refused (0 = unlimited).
+	QueueMaxDepth int
+	// DownloadWindow limits when fetching and downloading may start (local time).
+	DownloadWindow TimeWindow
+
+	// Per-phase deadlines for resolving the source link, extracting and placing
+	// files (DownloadTimeout bounds downloading); JobTimeout bounds one run of a
+	// whole job. Zero disables a limit.
+	ResolveTimeout time.Duration
+	ExtractTimeout time.Duration
+	PlaceTimeout   time.Duration
+	JobTimeout     time.Duration
+
+	// Optional pipeline steps run after placing an album: fetching its cover
+	// when the archive had none, rescanning the library index, and running
+	// PostImportHook through sh (empty disables it).
+	FetchCoverArt             bool
+	RefreshLibraryAfterImport bool
+	PostImportHook            string
+	PostImportHookTimeout     time.Duration
+
+	// DiskReserve is kept free on TEMP_DIR and NAVIDROME_MUSIC_PATH; jobs wait
+	// for space rather than eat into it.
+	DiskReserve uint64
+
+	// Each job works in its own dir under TempDir. A failed job's dir is kept
+	// for FailedWorkspaceRetention for debugging (0 removes it right away,
+	// and a manual retry then downloads everything again);
+	// dirs no live job owns are swept at startup and every
+	// WorkspaceSweepInterval (0 sweeps only at startup).
+	FailedWorkspaceRetention time.Duration
+	WorkspaceSweepInterval   time.Duration
 }
 
 // Load reads environment variables and returns a Config with defaults applied.
-func Load() Config {
+// Most invalid values fall back to their default; an invalid DOWNLOAD_WINDOW
+// is an error instead, since ignoring it would lift the restriction it sets,
+// and so is a search provider name used twice, since search cursors are keyed
+// by provider name.
+func Load() (Config, error) {
+	window, err := ParseTimeWindow(os.Getenv("DOWNLOAD_WINDOW"))
+	if err != nil {
+		return Config{}, fmt.Errorf("DOWNLOAD_WINDOW: %w", err)
+	}
+	amazonBaseURL := getEnv("AMAZON_API_BASE_URL", "")
+	providers := getProviders("SEARCH_PROVIDERS")
+	if err := checkProviderNames(amazonBaseURL != "", providers); err != nil {
+		return Config{}, fmt.Errorf("SEARCH_PROVIDERS: %w", err)
+	}
 	cfg := Config{
 		Port:             getEnv("PORT", "8080"),
 		DataDir:          getEnv("DATA_DIR", "data"),
@@ -29,7 +106,42 @@ func Load() Config {
 		ConcurrentJobs:   getInt("CONCURRENT_JOBS", 2),
 		EnableDownloads:  getBool("ENABLE_DOWNLOADS", false),
 		DownloadTimeout:  getDuration("DOWNLOAD_TIMEOUT", 10*time.Minute),
-		AmazonAPIBaseURL: getEnv("AMAZON_API_BASE_URL", ""),
+		AmazonAPIBaseURL: amazonBaseURL,
+		SearchCacheTTL:   getDuration("SEARCH_CACHE_TTL", 2*time.Minute),
+
+		SearchProviders:       providers,
+		SearchProviderTimeout: getDuration("SEARCH_PROVIDER_TIMEOUT", 8*time.Second),
+
+		ResolverBaseURL:          getEnv("RESOLVER_BASE_URL", "https://api.doubledouble.top"),
+		UpstreamRate:             getFloat("UPSTREAM_RATE", 2),
+		UpstreamBurst:            getInt("UPSTREAM_BURST", 4),
+		UpstreamMaxRetries:       getInt("UPSTREAM_MAX_RETRIES", 3),
+		UpstreamBreakerThreshold: getInt("UPSTREAM_BREAKER_THRESHOLD", 5),
+		UpstreamBreakerCooldown:  getDuration("UPSTREAM_BREAKER_COOLDOWN", 30*time.Second),
+
+		NetworkConcurrency: getInt("NETWORK_CONCURRENCY", 2),
+		DiskConcurrency:    getInt("DISK_CONCURRENCY", 1),
+
+		JobMaxAttempts:  getInt("JOB_MAX_ATTEMPTS", 3),
+		JobRetryBackoff: getDuration("JOB_RETRY_BACKOFF", 30*time.Second),
+
+		QueueMaxDepth:  getInt("QUEUE_MAX_DEPTH", 100),
+		DownloadWindow: window,
+
+		ResolveTimeout: getDuration("RESOLVE_TIMEOUT", 2*time.Minute),
+		ExtractTimeout: getDuration("EXTRACT_TIMEOUT", 10*time.Minute),
+		PlaceTimeout:   getDuration("PLACE_TIMEOUT", 10*time.Minute),
+		JobTimeout:     getDuration("JOB_TIMEOUT", 4*time.Hour),
+
+		FetchCoverArt:             getBool("FETCH_COVER_ART", false),
+		RefreshLibraryAfterImport: getBool("REFRESH_LIBRARY_AFTER_IMPORT", false),
+		PostImportHook:            getEnv("POST_IMPORT_HOOK", ""),
+		PostImportHookTimeout:     getDuration("POST_IMPORT_HOOK_TIMEOUT", time.Minute),
+
+		DiskReserve: getBytes("DISK_RESERVE", 1<<30),
+
+		FailedWorkspaceRetention: getDuration("FAILED_WORKSPACE_RETENTION", 24*time.Hour),
+		WorkspaceSweepInterval:   getDuration("WORKSPACE_SWEEP_INTERVAL", time.Hour),
 	}
 
 	// Ensure key directories exist.
@@ -41,7 +153,7 @@ func Load() Config {
 	cfg.DataDir = absOrDefault(cfg.DataDir)
 	cfg.TempDir = absOrDefault(cfg.TempDir)
 	cfg.NavidromePath = absOrDefault(cfg.NavidromePath)
-	return cfg
+	return cfg, nil
 }
 
 func getEnv(key, def string) string {
@@ -63,6 +175,17 @@ func getInt(key string, def int) int {
 	return def
 }
 
+func getFloat(key string, def float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	if f, err := strconv.ParseFloat(val, 64); err == nil {
+		return f
+	}
+	return def
+}
+
 func getBool(key string, def bool) bool {
 	val := os.Getenv(key)
 	if val == "" {
@@ -85,6 +208,57 @@ func getDuration(key string, def time.Duration) time.Duration {
 	return def
 }
 
+// getBytes parses a byte count with an optional K, M, G or T suffix (powers
+// of 1024; "GB", "GiB" and "G" are the same).
+func getBytes(key string, def uint64) uint64 {
+	val := strings.ToUpper(strings.TrimSpace(os.Getenv(key)))
+	if val == "" {
+		return def
+	}
+	val = strings.TrimSuffix(strings.TrimSuffix(val, "B"), "I")
+	mult := uint64(1)
+	if idx := strings.IndexAny(val, "KMGT"); idx >= 0 && idx == len(val)-1 {
+		mult = 1 << (10 * (strings.IndexByte("KMGT", val[idx]) + 1))
+		val = val[:idx]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil || n < 0 {
+		return def
+	}
+	return uint64(n * float64(mult))
+}
+
+// getProviders parses a comma-separated list of name=baseURL pairs.
+func getProviders(key string) []ProviderConfig {
+	var out []ProviderConfig
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		name, baseURL, ok := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.TrimSpace(name)
+		baseURL = strings.TrimSpace(baseURL)
+		if !ok || name == "" || baseURL == "" {
+			continue
+		}
+		out = append(out, ProviderConfig{Name: name, BaseURL: baseURL})
+	}
+	return out
+}
+
+// checkProviderNames rejects a provider name that is used twice, counting the
+// "amazon" provider that AMAZON_API_BASE_URL adds when amazon is set.
+func checkProviderNames(amazon bool, providers []ProviderConfig) error {
+	seen := map[string]bool{"amazon": amazon}
+	for _, p := range providers {
+		if seen[p.Name] {
+			if p.Name == "amazon" && amazon {
+				return fmt.Errorf("provider name %q is already used by AMAZON_API_BASE_URL", p.Name)
+			}
+			return fmt.Errorf("provider name %q is used more than once", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
 func absOrDefault(path string) string {
 	abs, err := filepath.Abs(path)
 	if err != nil {
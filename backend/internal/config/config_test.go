@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadRejectsDuplicateProviders(t *testing.T) {
+	tests := []struct {
+		amazon    string
+		providers string
+		wantErr   string
+	}{
+		{providers: "qobuz=http://q,tidal=http://t"},
+		{amazon: "http://a", providers: "qobuz=http://q"},
+		{providers: "amazon=http://a"},
+		{providers: "qobuz=http://q, qobuz=http://q2", wantErr: `"qobuz" is used more than once`},
+		{amazon: "http://a", providers: "amazon=http://a2", wantErr: `"amazon" is already used by AMAZON_API_BASE_URL`},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		t.Setenv("DATA_DIR", dir)
+		t.Setenv("TEMP_DIR", dir)
+		t.Setenv("NAVIDROME_MUSIC_PATH", dir)
+		t.Setenv("AMAZON_API_BASE_URL", tt.amazon)
+		t.Setenv("SEARCH_PROVIDERS", tt.providers)
+		_, err := Load()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Load(%q, %q) = %v", tt.amazon, tt.providers, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q, %q) = %v, want error containing %s", tt.amazon, tt.providers, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily local-time range such as 01:00-07:00. A window whose
+// end is before its start wraps past midnight (22:00-06:00). The zero value
+// is always open.
+type TimeWindow struct {
+	Start, End time.Duration // offsets from local midnight
+	set        bool
+}
+
+// ParseTimeWindow parses "HH:MM-HH:MM". An empty string yields the always-open window.
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return TimeWindow{}, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("time window %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("time window %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("time window %q: %w", s, err)
+	}
+	if start == end {
+		return TimeWindow{}, fmt.Errorf("time window %q: start and end are equal", s)
+	}
+	return TimeWindow{Start: start, End: end, set: true}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsSet reports whether the window restricts anything.
+func (w TimeWindow) IsSet() bool {
+	return w.set
+}
+
+// Contains reports whether t falls inside the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	if !w.set {
+		return true
+	}
+	offset := sinceMidnight(t)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns t if the window is open then, otherwise when it next opens.
+func (w TimeWindow) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	midnight := t.Add(-sinceMidnight(t))
+	open := midnight.Add(w.Start)
+	if !open.After(t) {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open
+}
+
+func (w TimeWindow) String() string {
+	if !w.set {
+		return ""
+	}
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
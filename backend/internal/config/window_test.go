@@ -0,0 +1,86 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "01:00-07:00", want: "01:00-07:00"},
+		{in: " 22:30 - 6:15 ", want: "22:30-06:15"},
+		{in: "1:00-7:00pm", wantErr: true},
+		{in: "01:00", wantErr: true},
+		{in: "25:00-07:00", wantErr: true},
+		{in: "07:00-07:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		w, err := ParseTimeWindow(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeWindow(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && w.String() != tt.want {
+			t.Errorf("ParseTimeWindow(%q) = %q, want %q", tt.in, w.String(), tt.want)
+		}
+	}
+}
+
+func TestTimeWindowContainsAndNextOpen(t *testing.T) {
+	at := func(clock string) time.Time {
+		t.Helper()
+		c, err := time.ParseInLocation("15:04", clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2026, 3, 10, c.Hour(), c.Minute(), 0, 0, time.Local)
+	}
+	day := func(clock string, days int) time.Time {
+		return at(clock).AddDate(0, 0, days)
+	}
+	mustParse := func(s string) TimeWindow {
+		w, err := ParseTimeWindow(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	tests := []struct {
+		window string
+		now    string
+		open   bool
+		next   time.Time
+	}{
+		{window: "", now: "12:00", open: true, next: at("12:00")},
+		{window: "01:00-07:00", now: "01:00", open: true, next: at("01:00")},
+		{window: "01:00-07:00", now: "06:59", open: true, next: at("06:59")},
+		{window: "01:00-07:00", now: "07:00", open: false, next: day("01:00", 1)},
+		{window: "01:00-07:00", now: "00:30", open: false, next: at("01:00")},
+		{window: "22:00-06:00", now: "23:00", open: true, next: at("23:00")},
+		{window: "22:00-06:00", now: "05:00", open: true, next: at("05:00")},
+		{window: "22:00-06:00", now: "12:00", open: false, next: at("22:00")},
+	}
+	for _, tt := range tests {
+		w := mustParse(tt.window)
+		now := at(tt.now)
+		if got := w.Contains(now); got != tt.open {
+			t.Errorf("%q.Contains(%s) = %v, want %v", tt.window, tt.now, got, tt.open)
+		}
+		if got := w.NextOpen(now); !got.Equal(tt.next) {
+			t.Errorf("%q.NextOpen(%s) = %s, want %s", tt.window, tt.now, got, tt.next)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidWindow(t *testing.T) {
+	t.Setenv("DOWNLOAD_WINDOW", "late-night")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted an invalid DOWNLOAD_WINDOW")
+	}
+}
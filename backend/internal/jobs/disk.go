@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"fmt"
+	"os"
+
+	"navidrome-helper/internal/util"
+)
+
+// DiskSpaceError reports a filesystem without room for a step plus DISK_RESERVE.
+type DiskSpaceError struct {
+	Path string
+	Free uint64
+	Need uint64
+}
+
+func (e *DiskSpaceError) Error() string {
+	return fmt.Sprintf("%s has %s free, %s needed", e.Path, util.FormatBytes(e.Free), util.FormatBytes(e.Need))
+}
+
+// diskCheck compares free space on TEMP_DIR and NAVIDROME_MUSIC_PATH with
+// what a step is about to write. Without a free func nothing is checked.
+type diskCheck struct {
+	tempDir  string
+	musicDir string
+	reserve  uint64
+	free     func(path string) (uint64, error)
+	// device identifies a path's filesystem; paths it cannot identify are
+	// checked on their own.
+	device func(path string) (uint64, error)
+}
+
+// require returns a *DiskSpaceError unless temp and music bytes fit on their
+// filesystems with the reserve to spare. When both directories live on the
+// same filesystem their needs are added up and the reserve counts once.
+// Unreadable free space never blocks.
+func (d diskCheck) require(temp, music uint64) error {
+	if d.free == nil {
+		return nil
+	}
+	type need struct {
+		path  string
+		bytes uint64
+	}
+	var needs []*need
+	byDevice := map[uint64]*need{}
+	for _, want := range []need{{d.tempDir, temp}, {d.musicDir, music}} {
+		if d.device != nil {
+			if dev, err := d.device(want.path); err == nil {
+				if shared, ok := byDevice[dev]; ok {
+					shared.bytes += want.bytes
+					continue
+				}
+				n := &need{want.path, want.bytes + d.reserve}
+				byDevice[dev] = n
+				needs = append(needs, n)
+				continue
+			}
+		}
+		needs = append(needs, &need{want.path, want.bytes + d.reserve})
+	}
+	for _, n := range needs {
+		free, err := d.free(n.path)
+		if err != nil {
+			continue
+		}
+		if free < n.bytes {
+			return &DiskSpaceError{Path: n.path, Free: free, Need: n.bytes}
+		}
+	}
+	return nil
+}
+
+// fileSize returns the size of path, or 0 when it does not exist.
+func fileSize(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Size())
+}
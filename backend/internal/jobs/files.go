@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"navidrome-helper/internal/library"
+)
+
+// extractZip unpacks archive into dest, rejecting entries that escape dest.
+func extractZip(ctx context.Context, archive, dest string) (int, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return 0, fmt.Errorf("create staging dir: %w", err)
+	}
+	root := filepath.Clean(dest) + string(os.PathSeparator)
+	count := 0
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		target := filepath.Join(dest, f.Name)
+		if !strings.HasPrefix(target, root) {
+			return count, fmt.Errorf("archive entry %q escapes staging dir", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return count, err
+			}
+			continue
+		}
+		if err := extractFile(f, target); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	return dst.Close()
+}
+
+// coverNames are artwork files kept alongside the audio.
+var coverNames = map[string]struct{}{
+	"cover.jpg": {}, "cover.png": {}, "folder.jpg": {}, "folder.png": {},
+}
+
+// moveAudioFiles flattens audio and cover files from staging into targetDir.
+// Tracks whose names clash across folders, as in multi-disc archives with
+// CD1/01.flac and CD2/01.flac, are prefixed with their folder ("CD1 - 01.flac").
+// Duplicate covers keep the first one found. Any other clash, including a
+// file already in targetDir, is an error so no track is lost silently.
+func moveAudioFiles(ctx context.Context, staging, targetDir string) (int, error) {
+	type staged struct{ path, rel string }
+	var files []staged
+	names := map[string]int{}
+	err := filepath.WalkDir(staging, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		_, cover := coverNames[strings.ToLower(d.Name())]
+		if !library.IsAudioFile(d.Name()) && !cover {
+			return nil
+		}
+		rel, err := filepath.Rel(staging, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, staged{path: path, rel: rel})
+		names[strings.ToLower(d.Name())]++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	placed := map[string]string{} // lower-cased destination name -> archive path
+	count := 0
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		name := filepath.Base(f.rel)
+		_, cover := coverNames[strings.ToLower(name)]
+		if dir := filepath.Dir(f.rel); !cover && dir != "." && names[strings.ToLower(name)] > 1 {
+			name = strings.ReplaceAll(dir, string(os.PathSeparator), " - ") + " - " + name
+		}
+		name = sanitizeName(name)
+		key := strings.ToLower(name)
+		if prev, ok := placed[key]; ok {
+			if cover {
+				continue
+			}
+			return count, fmt.Errorf("%s and %s would both be placed as %q", prev, f.rel, name)
+		}
+		dest := filepath.Join(targetDir, name)
+		if _, err := os.Stat(dest); err == nil {
+			return count, fmt.Errorf("%s already exists in %s", name, targetDir)
+		}
+		if err := moveFile(f.path, dest); err != nil {
+			return count, err
+		}
+		placed[key] = f.rel
+		count++
+	}
+	return count, nil
+}
+
+// moveFile renames src to dest, copying when they live on different filesystems.
+func moveFile(src, dest string) error {
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dest)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dest)
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractZip(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "a.zip")
+	writeZip(t, archive, map[string]string{
+		"Album/CD1/01 Intro.flac": "a",
+		"Album/CD2/01 Intro.flac": "b",
+		"Album/cover.jpg":         "c",
+	})
+	dest := filepath.Join(dir, "staging")
+	n, err := extractZip(context.Background(), archive, dest)
+	if err != nil || n != 3 {
+		t.Fatalf("extractZip = %d, %v", n, err)
+	}
+	for name, want := range map[string]string{"Album/CD1/01 Intro.flac": "a", "Album/CD2/01 Intro.flac": "b", "Album/cover.jpg": "c"} {
+		if body, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name))); err != nil || string(body) != want {
+			t.Errorf("%s = %q, %v", name, body, err)
+		}
+	}
+}
+
+func TestExtractZipRejectsEscapingEntries(t *testing.T) {
+	for _, name := range []string{"../evil.flac", "Album/../../evil.flac"} {
+		dir := t.TempDir()
+		archive := filepath.Join(dir, "a.zip")
+		writeZip(t, archive, map[string]string{name: "x"})
+		if _, err := extractZip(context.Background(), archive, filepath.Join(dir, "staging")); err == nil {
+			t.Errorf("%s: extracted without an error", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil.flac")); err == nil {
+			t.Errorf("%s: file written outside the staging dir", name)
+		}
+	}
+}
+
+func TestExtractZipRejectsBrokenArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "a.zip")
+	if err := os.WriteFile(archive, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := extractZip(context.Background(), archive, t.TempDir()); err == nil || !strings.Contains(err.Error(), "open archive") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestMoveAudioFiles(t *testing.T) {
+	staging := t.TempDir()
+	target := t.TempDir()
+	for name, body := range map[string]string{
+		"Album/01 Intro.flac": "a", "Album/Scans/back.jpg": "s", "Album/notes.txt": "n", "Album/folder.jpg": "c",
+	} {
+		path := filepath.Join(staging, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := moveAudioFiles(context.Background(), staging, target)
+	if err != nil || n != 2 {
+		t.Fatalf("moveAudioFiles = %d, %v", n, err)
+	}
+	// Nested folders are flattened; only audio and covers are kept.
+	if got := listDir(t, target); strings.Join(got, ",") != "01 Intro.flac,folder.jpg" {
+		t.Errorf("placed files = %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(staging, "Album", "01 Intro.flac")); !os.IsNotExist(err) {
+		t.Error("moved file still in staging")
+	}
+}
+
+func TestMoveAudioFilesReportsCollisions(t *testing.T) {
+	staging := t.TempDir()
+	target := t.TempDir()
+	for _, name := range []string{"01.flac", "CD1/01.flac"} {
+		path := filepath.Join(staging, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The root track keeps its name; the nested one is prefixed.
+	if n, err := moveAudioFiles(context.Background(), staging, target); err != nil || n != 2 {
+		t.Fatalf("moveAudioFiles = %d, %v", n, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(staging, "02.flac"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "02.flac"), []byte("y"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moveAudioFiles(context.Background(), staging, target); err == nil {
+		t.Error("existing file in the album folder was not reported")
+	}
+}
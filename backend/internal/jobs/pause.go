@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"navidrome-helper/internal/store"
+)
+
+// settingQueuePaused is the settings key holding the queue-wide pause flag.
+const settingQueuePaused = "queue_paused"
+
+var (
+	// ErrNotPausable is returned when pausing a job that is not queued or running.
+	ErrNotPausable = errors.New("only queued or running jobs can be paused")
+	// ErrNotPaused is returned when resuming a job that is not paused.
+	ErrNotPaused = errors.New("job is not paused")
+
+	// errPaused stops a job at a phase boundary so it can be parked.
+	errPaused = errors.New("paused")
+	// errOutsideWindow stops a job before a network phase outside DOWNLOAD_WINDOW.
+	errOutsideWindow = errors.New("outside download window")
+)
+
+func (r *Runner) loadPaused() {
+	value, _, err := r.store.GetSetting(settingQueuePaused)
+	if err != nil {
+		log.Printf("load pause state: %v", err)
+		return
+	}
+	paused, _ := strconv.ParseBool(value)
+	r.paused.Store(paused)
+}
+
+// Paused reports whether the queue is paused.
+func (r *Runner) Paused() bool {
+	return r.paused.Load()
+}
+
+// Pause stops workers from claiming jobs. Running jobs finish their current
+// phase and go back to the queue with their checkpoints.
+func (r *Runner) Pause() error {
+	if err := r.store.SetSetting(settingQueuePaused, "true"); err != nil {
+		return err
+	}
+	r.paused.Store(true)
+	log.Printf("job queue paused")
+	return nil
+}
+
+// Resume lets workers claim jobs again.
+func (r *Runner) Resume() error {
+	if err := r.store.SetSetting(settingQueuePaused, "false"); err != nil {
+		return err
+	}
+	r.paused.Store(false)
+	log.Printf("job queue resumed")
+	r.Notify()
+	return nil
+}
+
+// PauseJob holds a single job back. A queued job is paused at once; a running
+// one stops at its next phase boundary.
+func (r *Runner) PauseJob(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[id]; ok {
+		r.pausing[id] = true
+		_ = r.store.AddJobLog(id, "Pause requested; stopping after the current phase")
+		return nil
+	}
+	// A running job that no worker owns yet was just claimed; the worker
+	// skips it once it sees the paused status.
+	ok, err := r.store.UpdateJobStateIf(id, []string{StatusQueued, StatusRunning, StatusScheduled, StatusWaitingWindow, StatusWaitingDisk}, StatusPaused, PhasePaused, "Paused")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotPausable
+	}
+	_ = r.store.AddJobLog(id, "Paused")
+	return nil
+}
+
+// ResumeJob puts a paused job back in the queue, or withdraws a pause request
+// that a running job has not reached yet. Like Retry, it returns a
+// *DuplicateError when another active job covers one of its unfinished albums.
+func (r *Runner) ResumeJob(id string) error {
+	r.submitMu.Lock()
+	defer r.submitMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[id]; ok {
+		if !r.pausing[id] {
+			return ErrNotPaused
+		}
+		delete(r.pausing, id)
+		_ = r.store.AddJobLog(id, "Pause request withdrawn")
+		return nil
+	}
+	job, err := r.store.GetJob(id)
+	if err != nil {
+		return err
+	}
+	if job != nil && job.Status == StatusPaused {
+		if err := r.checkRequeue(job); err != nil {
+			return err
+		}
+	}
+	status, msg := StatusQueued, "Resumed"
+	if job != nil && job.RunAt != nil && job.RunAt.After(time.Now()) {
+		status, msg = StatusScheduled, fmt.Sprintf("Resumed; scheduled for %s", job.RunAt.Local().Format(time.RFC3339))
+	}
+	ok, err := r.store.UpdateJobStateIf(id, []string{StatusPaused}, status, PhaseQueued, msg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotPaused
+	}
+	_ = r.store.AddJobLog(id, msg)
+	r.Notify()
+	return nil
+}
+
+// pauseRequested reports whether a running job should stop before its next
+// phase, because it or the whole queue was paused.
+func (r *Runner) pauseRequested(id string) bool {
+	if r.Paused() {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pausing[id]
+}
+
+// networkGate returns errPaused or errOutsideWindow when a fetching or
+// downloading phase must not start now.
+func (r *Runner) networkGate(id string) error {
+	if r.pauseRequested(id) {
+		return errPaused
+	}
+	if !r.cfg.DownloadWindow.Contains(time.Now()) {
+		return errOutsideWindow
+	}
+	return nil
+}
+
+// waitForWindow parks job before item idx's next network phase until
+// DOWNLOAD_WINDOW opens. Workers pick it up again once it does.
+func (r *Runner) waitForWindow(job *store.Job, idx int) error {
+	item := &job.Items[idx]
+	item.Status, item.Message = StatusQueued, "Waiting for download window"
+	_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusQueued, item.Message)
+
+	opens := r.cfg.DownloadWindow.NextOpen(time.Now())
+	msg := fmt.Sprintf("Waiting for download window %s (opens %s)", r.cfg.DownloadWindow, opens.Format("15:04"))
+	_ = r.store.AddJobLog(job.ID, msg)
+	if err := r.store.UpdateJobState(job.ID, StatusWaitingWindow, PhaseWaitingWindow, msg, job.Progress, false); err != nil {
+		return err
+	}
+	log.Printf("job %s waiting for download window", job.ID)
+	return nil
+}
+
+// waitForDisk parks job before item idx's next step until there is disk
+// space; workers check again after diskRecheckInterval. Only the first check
+// that comes up short is logged.
+func (r *Runner) waitForDisk(job *store.Job, idx int, cause *DiskSpaceError) error {
+	item := &job.Items[idx]
+	item.Status, item.Message = StatusQueued, "Waiting for disk space"
+	_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusQueued, item.Message)
+
+	next := time.Now().Add(diskRecheckInterval)
+	job.NextRetryAt = &next
+	_ = r.store.UpdateJobAttempts(job.ID, job.Attempts, &next)
+	msg := fmt.Sprintf("Waiting for disk space: %v", cause)
+	if job.Phase != PhaseWaitingDisk {
+		_ = r.store.AddJobLog(job.ID, msg)
+		log.Printf("job %s waiting for disk space: %v", job.ID, cause)
+	}
+	return r.store.UpdateJobState(job.ID, StatusWaitingDisk, PhaseWaitingDisk, msg, job.Progress, false)
+}
+
+// park stops job before item idx's next phase. Completed phases stay
+// checkpointed; the job is paused itself or, when the whole queue was paused,
+// queued again for after Resume.
+func (r *Runner) park(job *store.Job, idx int) error {
+	r.mu.Lock()
+	own := r.pausing[job.ID]
+	r.mu.Unlock()
+
+	item := &job.Items[idx]
+	item.Status, item.Message = StatusQueued, "Paused"
+	_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusQueued, "Paused")
+
+	status, msg := StatusQueued, "Queue paused; waiting for resume"
+	switch {
+	case own:
+		status, msg = StatusPaused, "Paused"
+	case !r.Paused():
+		// The pause was lifted while the job was stopping.
+		msg = "Pause lifted; queued again"
+		defer r.Notify()
+	}
+	_ = r.store.AddJobLog(job.ID, msg)
+	if err := r.store.UpdateJobState(job.ID, status, PhasePaused, msg, job.Progress, false); err != nil {
+		return err
+	}
+	log.Printf("job %s stopped before its next phase: %s", job.ID, msg)
+	return nil
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"navidrome-helper/internal/config"
+)
+
+func TestPausedQueueClaimsNothing(t *testing.T) {
+	step := funcStep{phase: PhaseFetchingSource, runs: map[string]int{}}
+	r := newTestRunner(t, config.Config{ConcurrentJobs: 1})
+	r.steps = []Step{step}
+	r.starts = stepStarts(r.steps)
+	if err := r.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if !NewRunner(r.store, r.cfg, nil).Paused() {
+		t.Error("a new runner did not load the stored pause")
+	}
+	job := insertJob(t, r, StatusQueued, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Start(ctx)
+	defer func() {
+		cancel()
+		r.Wait()
+	}()
+	r.Notify()
+	time.Sleep(100 * time.Millisecond)
+	if status, _ := r.store.GetJobStatus(job.ID); status != StatusQueued {
+		t.Fatalf("status while the queue is paused = %s, want queued", status)
+	}
+
+	if err := r.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := r.store.GetJobStatus(job.ID)
+		if status == StatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status after resuming the queue = %s, want completed", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPauseAndResumeJob(t *testing.T) {
+	tests := []struct {
+		status     string
+		finished   bool
+		pauseErr   error
+		wantStatus string
+	}{
+		{status: StatusQueued, wantStatus: StatusQueued},
+		{status: StatusWaitingWindow, wantStatus: StatusQueued},
+		{status: StatusWaitingDisk, wantStatus: StatusQueued},
+		{status: StatusPlanned, pauseErr: ErrNotPausable},
+		{status: StatusCompleted, finished: true, pauseErr: ErrNotPausable},
+	}
+	r := newTestRunner(t, config.Config{})
+	for _, tt := range tests {
+		job := insertJob(t, r, tt.status, tt.finished)
+		if err := r.PauseJob(job.ID); !errors.Is(err, tt.pauseErr) {
+			t.Errorf("PauseJob(%s) = %v, want %v", tt.status, err, tt.pauseErr)
+			continue
+		}
+		if tt.pauseErr != nil {
+			if err := r.ResumeJob(job.ID); !errors.Is(err, ErrNotPaused) {
+				t.Errorf("ResumeJob(%s) = %v, want ErrNotPaused", tt.status, err)
+			}
+			continue
+		}
+		if status, _ := r.store.GetJobStatus(job.ID); status != StatusPaused {
+			t.Errorf("PauseJob(%s) left status %s", tt.status, status)
+		}
+		// A paused job is not claimed.
+		if claimed, err := r.store.ClaimJob(time.Now(), r.claimable(time.Now())...); err != nil || claimed != nil {
+			t.Errorf("ClaimJob with a paused %s job = %v, %v", tt.status, claimed, err)
+		}
+		if err := r.ResumeJob(job.ID); err != nil {
+			t.Errorf("ResumeJob(%s) = %v", tt.status, err)
+			continue
+		}
+		if status, _ := r.store.GetJobStatus(job.ID); status != tt.wantStatus {
+			t.Errorf("ResumeJob(%s) left status %s, want %s", tt.status, status, tt.wantStatus)
+		}
+		// Resuming requeues the job, so it must not count as a duplicate of
+		// the next case, which imports the same album.
+		if err := r.Cancel(job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
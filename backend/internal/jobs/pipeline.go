@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/library"
+	"navidrome-helper/internal/source"
+	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/util"
+)
+
+// Slot is the pool of concurrency slots a step runs in.
+type Slot int
+
+const (
+	// SlotNone steps run without taking a slot.
+	SlotNone Slot = iota
+	// SlotNetwork steps share NETWORK_CONCURRENCY and only start while the
+	// queue is unpaused and DOWNLOAD_WINDOW is open.
+	SlotNetwork
+	// SlotDisk steps share DISK_CONCURRENCY.
+	SlotDisk
+)
+
+// StepInfo describes a step to the runner.
+type StepInfo struct {
+	// Phase names the step; it becomes the job phase while the step runs and
+	// the item's checkpoint once it completes.
+	Phase string
+	// Message is logged when the step starts.
+	Message string
+	// Weight is the step's share of an item's progress, relative to the
+	// other steps in the pipeline.
+	Weight  float64
+	Slot    Slot
+	Timeout time.Duration // zero means no limit
+}
+
+// Step is one phase of the per-item pipeline. The runner takes care of
+// slots, pausing, timeouts, progress and checkpoints around Run.
+type Step interface {
+	Info() StepInfo
+	Run(ctx context.Context, it *StepItem) error
+}
+
+// Preflighter is implemented by steps that check preconditions before they
+// start. A *DiskSpaceError parks the job as waiting_disk until there is room.
+type Preflighter interface {
+	Preflight(ctx context.Context, it *StepItem) error
+}
+
+// Planner is implemented by steps that take part in dry runs. Plan fills in
+// the item's plan fields without downloading anything or writing under
+// NAVIDROME_MUSIC_PATH.
+type Planner interface {
+	Plan(ctx context.Context, it *StepItem) error
+}
+
+// StepItem is the album a step works on.
+type StepItem struct {
+	Job  *store.Job
+	Item *store.JobItem
+	// Archive and Staging are the item's temp download and extraction paths;
+	// Target is its album folder under NAVIDROME_MUSIC_PATH.
+	Archive string
+	Staging string
+	Target  string
+	// Skipped is set when the album folder already existed and nothing was
+	// placed. It is stored on the item, so it survives a resume past placing.
+	Skipped bool
+	// Summary becomes the item's message once every step has run.
+	Summary string
+
+	log func(msg string)
+}
+
+// Logf appends a line to the job log.
+func (it *StepItem) Logf(format string, args ...any) {
+	if it.log != nil {
+		it.log(fmt.Sprintf(format, args...))
+	}
+}
+
+// phaseOrder lists every built-in phase in pipeline order. Checkpoints are
+// compared by it, so an item keeps its progress when optional steps are
+// switched on or off between runs.
+var phaseOrder = []string{
+	PhaseFetchingSource, PhaseDownloading, PhaseExtracting, PhasePlacing,
+	PhaseCoverArt, PhaseRefreshLibrary, PhasePostImportHook, PhaseCleanup,
+}
+
+// NewPipeline returns the built-in steps: fetching, downloading, extracting
+// and placing, the optional steps enabled in cfg, and cleanup.
+func NewPipeline(cfg config.Config, resolver source.Resolver, downloader source.Downloader, indexer *library.Indexer) []Step {
+	disk := diskCheck{tempDir: cfg.TempDir, musicDir: cfg.NavidromePath, reserve: cfg.DiskReserve,
+		free: util.FreeSpace, device: util.DeviceID}
+	steps := []Step{
+		resolveStep{resolver: resolver, timeout: cfg.ResolveTimeout},
+		downloadStep{downloader: downloader, disk: disk, timeout: cfg.DownloadTimeout, probeTimeout: cfg.ResolveTimeout},
+		extractStep{disk: disk, timeout: cfg.ExtractTimeout},
+		placeStep{timeout: cfg.PlaceTimeout},
+	}
+	if cfg.FetchCoverArt {
+		steps = append(steps, coverArtStep{downloader: downloader, timeout: cfg.DownloadTimeout})
+	}
+	if cfg.RefreshLibraryAfterImport && indexer != nil {
+		steps = append(steps, refreshStep{indexer: indexer})
+	}
+	if cfg.PostImportHook != "" {
+		steps = append(steps, hookStep{command: cfg.PostImportHook, timeout: cfg.PostImportHookTimeout})
+	}
+	return append(steps, cleanupStep{})
+}
+
+// stepStarts returns how far an item is when it enters each step, from the
+// step weights.
+func stepStarts(steps []Step) []float64 {
+	total := 0.0
+	for _, step := range steps {
+		total += step.Info().Weight
+	}
+	starts := make([]float64, len(steps))
+	done := 0.0
+	for idx, step := range steps {
+		if total > 0 {
+			starts[idx] = done / total
+		}
+		done += step.Info().Weight
+	}
+	return starts
+}
@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"navidrome-helper/internal/store"
+)
+
+const (
+	// ConflictExists marks an item whose album folder already exists; the run
+	// would skip it.
+	ConflictExists = "exists"
+	// ConflictQueued marks an item that a queued, paused or running job
+	// already covers.
+	ConflictQueued = "queued"
+	// ConflictDuplicate marks an item placed into the same folder as an earlier
+	// item of the same import.
+	ConflictDuplicate = "duplicate"
+)
+
+// ErrNotPlanned is returned when confirming a job that is not a pending dry run.
+var ErrNotPlanned = errors.New("job is not a planned dry run")
+
+// Plan is the outcome of a dry run.
+type Plan struct {
+	Items []store.JobItem `json:"items"`
+	// EstimatedSize sums the known archive sizes in bytes.
+	EstimatedSize int64 `json:"estimatedSize"`
+	Conflicts     int   `json:"conflicts"`
+	Unresolved    int   `json:"unresolved"`
+}
+
+// Plan dry-runs a planned job: each item goes through the steps that
+// implement Planner, which resolve its source and work out its target path,
+// conflicts and size. Nothing is downloaded or written under
+// NAVIDROME_MUSIC_PATH. The outcome is stored on the items and the job stays
+// planned until Confirm.
+func (r *Runner) Plan(ctx context.Context, job *store.Job) (*Plan, error) {
+	plan := &Plan{}
+	covered, err := r.activeItems(job)
+	if err != nil {
+		return nil, r.failPlan(job, err)
+	}
+	targets := map[string]bool{}
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		it := r.stepItem(job, item)
+		var errs []string
+		for _, s := range r.steps {
+			planner, ok := s.(Planner)
+			if !ok {
+				continue
+			}
+			info := s.Info()
+			err := runPhase(ctx, info.Phase, info.Timeout, func(ctx context.Context) error { return planner.Plan(ctx, it) })
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, r.failPlan(job, ctx.Err())
+				}
+				errs = append(errs, err.Error())
+			}
+		}
+		if _, ok := covered[idx]; ok {
+			item.Conflict = ConflictQueued
+		} else if item.Conflict == "" && targets[item.TargetPath] {
+			item.Conflict = ConflictDuplicate
+		}
+		targets[item.TargetPath] = true
+
+		item.Message = planMessage(item, covered[idx], errs)
+		if err := r.store.UpdateJobItemPlan(job.ID, item); err != nil {
+			return nil, r.failPlan(job, err)
+		}
+		plan.EstimatedSize += item.EstimatedSize
+		if item.Conflict != "" {
+			plan.Conflicts++
+		}
+		if item.SourceURL == "" {
+			plan.Unresolved++
+		}
+	}
+	plan.Items = job.Items
+
+	msg := fmt.Sprintf("Dry run: %d albums, %d conflicts, %d unresolved; confirm to import", len(job.Items), plan.Conflicts, plan.Unresolved)
+	_ = r.store.AddJobLog(job.ID, msg)
+	if err := r.store.UpdateJobState(job.ID, StatusPlanned, PhasePlanned, msg, 0, false); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// failPlan ends a dry run that could not be completed. It is cancelled rather
+// than failed, so Retry cannot turn an unconfirmed plan into an import.
+func (r *Runner) failPlan(job *store.Job, err error) error {
+	msg := fmt.Sprintf("Dry run failed: %v", err)
+	_ = r.store.AddJobLog(job.ID, msg)
+	_ = r.store.UpdateJobState(job.ID, StatusCancelled, PhaseCancelled, msg, 0, true)
+	return err
+}
+
+func planMessage(item *store.JobItem, coveredBy string, errs []string) string {
+	switch {
+	case coveredBy != "":
+		return fmt.Sprintf("Job %s is already importing this album", coveredBy)
+	case len(errs) > 0:
+		return strings.Join(errs, "; ")
+	case item.Conflict == ConflictExists:
+		return fmt.Sprintf("Album already exists at %s; it will be skipped", item.TargetPath)
+	case item.Conflict == ConflictDuplicate:
+		return "Same album folder as an earlier item; it will be skipped"
+	}
+	return fmt.Sprintf("Will be placed at %s", item.TargetPath)
+}
+
+// Confirm queues a planned job. Items that an active job has started to
+// cover since the dry run are left out, as in Submit; when every item is
+// covered the plan is cancelled instead. It then runs like any other import
+// and resolves its sources again, since resolved links may expire.
+// ErrQueueFull is returned when the job would exceed QUEUE_MAX_DEPTH.
+func (r *Runner) Confirm(id string) ([]Duplicate, error) {
+	r.submitMu.Lock()
+	defer r.submitMu.Unlock()
+
+	job, err := r.store.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.Status != StatusPlanned {
+		return nil, ErrNotPlanned
+	}
+	dups, err := r.dropCovered(job)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.Items) == 0 {
+		msg := fmt.Sprintf("Job %s is already importing these albums", dups[0].JobID)
+		if err := r.store.UpdateJobState(id, StatusCancelled, PhaseCancelled, msg, 0, true); err != nil {
+			return nil, err
+		}
+		_ = r.store.AddJobLog(id, msg)
+		return dups, nil
+	}
+	if err := r.Admit(); err != nil {
+		return nil, err
+	}
+	if len(dups) > 0 {
+		ids := make([]string, len(dups))
+		for idx, dup := range dups {
+			ids[idx] = dup.SourceID
+			_ = r.store.AddJobLog(id, fmt.Sprintf("Left out %s: job %s is already importing it", dup.SourceID, dup.JobID))
+		}
+		if err := r.store.RemoveJobItems(id, ids, job.Artist, job.Album); err != nil {
+			return nil, err
+		}
+	}
+	status, msg := StatusQueued, "Confirmed"
+	if job.RunAt != nil && job.RunAt.After(time.Now()) {
+		status, msg = StatusScheduled, fmt.Sprintf("Confirmed; scheduled for %s", job.RunAt.Local().Format(time.RFC3339))
+	}
+	ok, err := r.store.UpdateJobStateIf(id, []string{StatusPlanned}, status, PhaseQueued, msg)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotPlanned
+	}
+	_ = r.store.AddJobLog(id, msg)
+	r.Notify()
+	return dups, nil
+}
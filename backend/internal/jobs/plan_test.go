@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"navidrome-helper/internal/config"
+)
+
+// newPlanRunner returns a test runner whose steps resolve every item to a
+// 100-byte archive and work out its album folder.
+func newPlanRunner(t *testing.T) *Runner {
+	t.Helper()
+	r := newTestRunner(t, config.Config{})
+	r.steps = []Step{resolveStep{resolver: sizedResolver{url: "https://files/", size: 100}}, placeStep{}}
+	r.starts = stepStarts(r.steps)
+	return r
+}
+
+func TestPlanThenConfirm(t *testing.T) {
+	r := newPlanRunner(t)
+	other := newImport("two")
+	if _, err := r.Submit(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(r.cfg.NavidromePath, "A", "three"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	job := newImport("one", "two", "three")
+	job.Status, job.Phase = StatusPlanned, PhasePlanned
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := r.Plan(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.EstimatedSize != 300 || plan.Conflicts != 2 || plan.Unresolved != 0 {
+		t.Errorf("plan = size %d, %d conflicts, %d unresolved", plan.EstimatedSize, plan.Conflicts, plan.Unresolved)
+	}
+	wantConflicts := []string{"", ConflictQueued, ConflictExists}
+	stored, err := r.store.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, item := range stored.Items {
+		if item.Conflict != wantConflicts[idx] || item.SourceURL != "https://files/"+item.SourceID {
+			t.Errorf("planned %s: conflict %q, source %q", item.SourceID, item.Conflict, item.SourceURL)
+		}
+	}
+	if stored.Status != StatusPlanned {
+		t.Errorf("status after planning = %s, want planned", stored.Status)
+	}
+	if entries, _ := os.ReadDir(r.cfg.TempDir); len(entries) != 0 {
+		t.Errorf("planning wrote %d entries under TEMP_DIR", len(entries))
+	}
+
+	// Confirming leaves out the album the other job is importing.
+	dups, err := r.Confirm(job.ID)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(dups) != 1 || dups[0].SourceID != "two" || dups[0].JobID != other.ID {
+		t.Errorf("duplicates = %+v, want two covered by %s", dups, other.ID)
+	}
+	confirmed, err := r.store.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed.Status != StatusQueued || len(confirmed.Items) != 2 {
+		t.Errorf("confirmed job is %s with %d items, want queued with 2", confirmed.Status, len(confirmed.Items))
+	}
+}
+
+func TestConfirmRejectsJobsThatAreNotPlanned(t *testing.T) {
+	r := newPlanRunner(t)
+	planned := newImport("one")
+	planned.Status, planned.Phase = StatusPlanned, PhasePlanned
+	if err := r.store.InsertJob(planned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Confirm(planned.ID); err != nil {
+		t.Fatalf("first Confirm: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"confirmed twice", planned.ID},
+		{"queued", insertJob(t, r, StatusQueued, false).ID},
+		{"cancelled", insertJob(t, r, StatusCancelled, true).ID},
+		{"unknown", "no-such-job"},
+	}
+	for _, tt := range tests {
+		if _, err := r.Confirm(tt.id); !errors.Is(err, ErrNotPlanned) {
+			t.Errorf("Confirm(%s) = %v, want ErrNotPlanned", tt.name, err)
+		}
+	}
+}
+
+func TestConfirmCancelsFullyCoveredPlan(t *testing.T) {
+	r := newPlanRunner(t)
+	job := newImport("one", "two")
+	job.Status, job.Phase = StatusPlanned, PhasePlanned
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Plan(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	// Another import takes both albums before the plan is confirmed.
+	other := newImport("one", "two")
+	if _, err := r.Submit(other); err != nil {
+		t.Fatal(err)
+	}
+
+	dups, err := r.Confirm(job.ID)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(dups) != 2 || dups[0].JobID != other.ID {
+		t.Errorf("duplicates = %+v, want both covered by %s", dups, other.ID)
+	}
+	if status, _ := r.store.GetJobStatus(job.ID); status != StatusCancelled {
+		t.Errorf("status = %s, want cancelled", status)
+	}
+}
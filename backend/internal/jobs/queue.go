@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"navidrome-helper/internal/store"
+)
+
+const (
+	// defaultJobDuration is assumed per job until enough jobs have completed.
+	defaultJobDuration = 2 * time.Minute
+	// durationSamples is how many recent completed jobs feed the estimate.
+	durationSamples = 20
+)
+
+// QueueEntry is a queued job with its place in line.
+type QueueEntry struct {
+	store.Job
+	Position       int       `json:"position"`
+	EstimatedStart time.Time `json:"estimatedStart"`
+}
+
+// QueueView is the runner's queue as reported by GET /api/queue.
+type QueueView struct {
+	// Paused means no job starts until the queue is resumed; estimates
+	// assume it resumes now.
+	Paused                 bool         `json:"paused"`
+	Running                []store.Job  `json:"running"`
+	Queued                 []QueueEntry `json:"queued"`
+	AverageDurationSeconds float64      `json:"averageDurationSeconds"`
+}
+
+// Queue lists running jobs and queued jobs in dequeue order. Start times are
+// estimated by handing queued jobs to workers as they free up, assuming each
+// job takes the average duration of recently completed ones.
+func (r *Runner) Queue(now time.Time) (*QueueView, error) {
+	running, err := r.store.ListRunningJobs()
+	if err != nil {
+		return nil, err
+	}
+	queued, err := r.store.ListQueuedJobs()
+	if err != nil {
+		return nil, err
+	}
+	avg, err := r.averageDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	// free[i] is when worker i is expected to be idle.
+	free := make([]time.Time, max(r.cfg.ConcurrentJobs, 1))
+	for i := range free {
+		free[i] = now
+		if i < len(running) && running[i].StartedAt != nil {
+			if end := running[i].StartedAt.Add(avg); end.After(now) {
+				free[i] = end
+			}
+		}
+	}
+
+	view := &QueueView{
+		Paused:                 r.Paused(),
+		Running:                append([]store.Job{}, running...),
+		Queued:                 make([]QueueEntry, 0, len(queued)),
+		AverageDurationSeconds: avg.Seconds(),
+	}
+	for idx, job := range queued {
+		next := 0
+		for i := range free {
+			if free[i].Before(free[next]) {
+				next = i
+			}
+		}
+		start := free[next]
+		if job.NextRetryAt != nil && job.NextRetryAt.After(start) {
+			start = *job.NextRetryAt
+		}
+		if job.RunAt != nil && job.RunAt.After(start) {
+			start = *job.RunAt
+		}
+		// Assume every queued job still has network phases to run.
+		start = r.cfg.DownloadWindow.NextOpen(start)
+		free[next] = start.Add(avg)
+		view.Queued = append(view.Queued, QueueEntry{Job: job, Position: idx + 1, EstimatedStart: start})
+	}
+	return view, nil
+}
+
+// SetPriority changes a job's place in the queue. Finished jobs return ErrJobFinished.
+func (r *Runner) SetPriority(id string, priority int) error {
+	ok, err := r.store.UpdateJobPriority(id, priority)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrJobFinished
+	}
+	_ = r.store.AddJobLog(id, fmt.Sprintf("Priority set to %d", priority))
+	return nil
+}
+
+func (r *Runner) averageDuration() (time.Duration, error) {
+	durations, err := r.store.RecentJobDurations(durationSamples)
+	if err != nil {
+		return 0, err
+	}
+	if len(durations) == 0 {
+		return defaultJobDuration, nil
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations)), nil
+}
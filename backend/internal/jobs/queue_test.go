@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"navidrome-helper/internal/config"
+)
+
+func TestSetPriorityReordersClaims(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	now := time.Now()
+	// Jobs are inserted in name order and claimed oldest first at equal priority.
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		job := insertJob(t, r, StatusQueued, false)
+		ids[name] = job.ID
+	}
+	byID := map[string]string{}
+	for name, id := range ids {
+		byID[id] = name
+	}
+
+	tests := []struct {
+		name     string
+		priority int
+	}{
+		{"c", 10},
+		{"a", -5},
+		{"d", 10},
+	}
+	for _, tt := range tests {
+		if err := r.SetPriority(ids[tt.name], tt.priority); err != nil {
+			t.Fatalf("SetPriority(%s, %d): %v", tt.name, tt.priority, err)
+		}
+	}
+
+	view, err := r.Queue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed string
+	for _, entry := range view.Queued {
+		listed += byID[entry.ID]
+	}
+	var claimed string
+	for {
+		job, err := r.store.ClaimJob(now, r.claimable(now)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil {
+			break
+		}
+		claimed += byID[job.ID]
+	}
+	// Higher priority first; c and d tie and keep their insertion order.
+	if want := "cdba"; claimed != want || listed != want {
+		t.Errorf("claim order = %q, queue order = %q, want %q", claimed, listed, want)
+	}
+
+	done := insertJob(t, r, StatusCompleted, true)
+	if err := r.SetPriority(done.ID, 1); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("SetPriority on a completed job = %v, want ErrJobFinished", err)
+	}
+}
@@ -2,14 +2,18 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/source"
 	"navidrome-helper/internal/store"
 )
 
@@ -18,125 +22,790 @@ const (
 	StatusRunning   = "running"
 	StatusCompleted = "completed"
 	StatusFailed    = "failed"
+	// StatusInterrupted marks a job cut off by a restart that could not be resumed safely.
+	StatusInterrupted = "interrupted"
+	StatusCancelled   = "cancelled"
+	// StatusPartial marks a job where some items were placed and others failed.
+	StatusPartial = "partial"
+	// StatusPaused marks a job held back by PauseJob until ResumeJob.
+	StatusPaused = "paused"
+	// StatusScheduled marks a job waiting for its runAt time.
+	StatusScheduled = "scheduled"
+	// StatusWaitingWindow marks a job whose next network phase waits for DOWNLOAD_WINDOW.
+	StatusWaitingWindow = "waiting_window"
+	// StatusWaitingDisk marks a job waiting for free space on TEMP_DIR or NAVIDROME_MUSIC_PATH.
+	StatusWaitingDisk = "waiting_disk"
+	// StatusPlanned marks a dry-run job waiting for Confirm.
+	StatusPlanned = "planned"
 
-	PhaseQueued          = "queued"
-	PhaseFetchingSource  = "fetching_source"
-	PhaseDownloading     = "downloading"
-	PhaseExtracting      = "extracting"
-	PhasePlacing         = "placing"
-	PhaseCleanup         = "cleanup"
-	PhaseCompleted       = "completed"
-	PhaseFailed          = "failed"
+	PhaseQueued         = "queued"
+	PhaseFetchingSource = "fetching_source"
+	PhaseDownloading    = "downloading"
+	PhaseExtracting     = "extracting"
+	PhasePlacing        = "placing"
+	PhaseCoverArt       = "cover_art"
+	PhaseRefreshLibrary = "refreshing_library"
+	PhasePostImportHook = "post_import_hook"
+	PhaseCleanup        = "cleanup"
+	PhaseCompleted      = "completed"
+	PhaseFailed         = "failed"
+	PhaseCancelled      = "cancelled"
+	// PhaseRetryWait marks a queued job waiting for its automatic retry.
+	PhaseRetryWait = "retry_wait"
+	PhasePaused    = "paused"
+	// PhaseWaitingWindow marks a job parked until the download window opens.
+	PhaseWaitingWindow = "waiting_window"
+	PhaseWaitingDisk   = "waiting_disk"
+	PhasePlanned       = "planned"
 )
 
-// Runner processes jobs asynchronously.
+const (
+	// maxRetryBackoff caps the doubling delay between automatic retries.
+	maxRetryBackoff = time.Hour
+	// pollInterval is how often idle workers look for jobs that became due
+	// (retries) without being notified.
+	pollInterval = time.Second
+	// diskRecheckInterval is how long a job waits for disk space between checks.
+	diskRecheckInterval = time.Minute
+)
+
+var (
+	// ErrCancelled is the cancellation cause for jobs stopped through Cancel.
+	ErrCancelled = errors.New("cancelled by user")
+	// ErrJobFinished is returned when cancelling a job that already ended.
+	ErrJobFinished = errors.New("job already finished")
+	// ErrNotRetryable is returned when retrying a job that has not failed.
+	ErrNotRetryable = errors.New("only failed, partial or interrupted jobs can be retried")
+	// ErrQueueFull is returned when QUEUE_MAX_DEPTH jobs are already waiting.
+	ErrQueueFull = errors.New("job queue is full")
+)
+
+// Runner processes jobs asynchronously with a pool of workers. Queued jobs live
+// in the jobs table; workers claim them one at a time and run each item
+// through the pipeline's steps. Network-bound steps (fetching, downloading)
+// and disk-bound steps (extracting, placing) are additionally capped by their
+// own slot pools.
 type Runner struct {
-	store *store.Store
-	cfg   config.Config
-	queue chan *store.Job
+	store   *store.Store
+	cfg     config.Config
+	steps   []Step
+	starts  []float64 // item progress on entering each step
+	wake    chan struct{}
+	network chan struct{}
+	disk    chan struct{}
+	wg      sync.WaitGroup
+
+	paused   atomic.Bool // queue-wide pause, persisted in settings
+	submitMu sync.Mutex  // serializes Submit's duplicate check and insert
+
+	mu      sync.Mutex
+	active  map[string]context.CancelCauseFunc // running jobs by id
+	pausing map[string]bool                    // running jobs asked to pause
 }
 
-func NewRunner(st *store.Store, cfg config.Config) *Runner {
-	return &Runner{
-		store: st,
-		cfg:   cfg,
-		queue: make(chan *store.Job, 16),
+// NewRunner returns a runner that takes every item through steps in order;
+// see NewPipeline.
+func NewRunner(st *store.Store, cfg config.Config, steps []Step) *Runner {
+	r := &Runner{
+		store:   st,
+		cfg:     cfg,
+		steps:   steps,
+		starts:  stepStarts(steps),
+		wake:    make(chan struct{}, 1),
+		network: make(chan struct{}, max(cfg.NetworkConcurrency, 1)),
+		disk:    make(chan struct{}, max(cfg.DiskConcurrency, 1)),
+		active:  map[string]context.CancelCauseFunc{},
+		pausing: map[string]bool{},
 	}
+	r.loadPaused()
+	return r
 }
 
-// Start begins processing jobs until the context is done.
+// Start recovers jobs left running by a previous process and sweeps orphaned
+// temp workspaces, then launches CONCURRENT_JOBS workers that process jobs until the context is done.
 func (r *Runner) Start(ctx context.Context) {
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case job := <-r.queue:
-				if job == nil {
-					continue
-				}
-				if err := r.handle(job); err != nil {
-					log.Printf("job %s failed: %v", job.ID, err)
-				}
+	// Recovery runs first: once workers claim jobs, a running row is no longer stale.
+	r.recoverJobs()
+	r.sweepWorkspaces(time.Now())
+	if r.cfg.WorkspaceSweepInterval > 0 {
+		r.wg.Add(1)
+		go r.sweepLoop(ctx)
+	}
+	workers := max(r.cfg.ConcurrentJobs, 1)
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.work(ctx)
+	}
+	log.Printf("job runner started with %d workers", workers)
+}
+
+// Wait blocks until every worker has returned after the Start context is cancelled.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		if !r.Paused() {
+			job, err := r.store.ClaimJob(time.Now(), r.claimable(time.Now())...)
+			if err != nil {
+				log.Printf("claim job: %v", err)
+			}
+			if job != nil {
+				// More jobs may be waiting; let another idle worker look.
+				r.Notify()
+				r.run(ctx, job)
+				continue
 			}
 		}
-	}()
+		select {
+		case <-ctx.Done():
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
 }
 
-func (r *Runner) Enqueue(job *store.Job) {
-	r.queue <- job
+// claimable lists the statuses workers may claim at now: jobs parked for the
+// download window only while it is open. Jobs waiting for disk space are
+// claimed once their recheck is due.
+func (r *Runner) claimable(now time.Time) []string {
+	statuses := []string{StatusQueued, StatusScheduled, StatusWaitingDisk}
+	if r.cfg.DownloadWindow.Contains(now) {
+		statuses = append(statuses, StatusWaitingWindow)
+	}
+	return statuses
 }
 
-func (r *Runner) handle(job *store.Job) error {
-	if err := r.store.UpdateJobState(job.ID, StatusRunning, PhaseFetchingSource, "Fetching pixeldrain link via doubledouble.top (stubbed)", 0.05, false); err != nil {
-		return err
+// Notify wakes an idle worker after a job was queued.
+func (r *Runner) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
 	}
-	_ = r.store.AddJobLog(job.ID, "Fetching pixeldrain link via doubledouble.top (stubbed)")
-	time.Sleep(300 * time.Millisecond)
+}
 
-	if err := r.store.UpdateJobState(job.ID, StatusRunning, PhaseDownloading, "Downloading zip (stubbed)", 0.2, false); err != nil {
+// Admit reports ErrQueueFull when QUEUE_MAX_DEPTH jobs are already queued.
+func (r *Runner) Admit() error {
+	if r.cfg.QueueMaxDepth <= 0 {
+		return nil
+	}
+	depth, err := r.store.CountQueuedJobs()
+	if err != nil {
 		return err
 	}
-	_ = r.store.AddJobLog(job.ID, "Downloading zip (stubbed)")
-	time.Sleep(300 * time.Millisecond)
+	if depth >= r.cfg.QueueMaxDepth {
+		return ErrQueueFull
+	}
+	return nil
+}
 
-	if err := r.store.UpdateJobState(job.ID, StatusRunning, PhaseExtracting, "Extracting archive (stubbed)", 0.45, false); err != nil {
-		return err
+// run handles one job under its own cancellable context, bounded by JOB_TIMEOUT.
+func (r *Runner) run(ctx context.Context, job *store.Job) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if r.cfg.JobTimeout > 0 {
+		var stop context.CancelFunc
+		jobCtx, stop = context.WithTimeoutCause(jobCtx, r.cfg.JobTimeout, errJobTimeout)
+		defer stop()
+	}
+
+	// Registering and checking the status under r.mu pairs with Cancel, so a
+	// job is either skipped here or cancelled through its context, never both.
+	r.mu.Lock()
+	status, err := r.store.GetJobStatus(job.ID)
+	if err != nil || (status != StatusQueued && status != StatusRunning) {
+		r.mu.Unlock()
+		return
 	}
-	_ = r.store.AddJobLog(job.ID, "Extracting archive (stubbed)")
-	time.Sleep(300 * time.Millisecond)
+	r.active[job.ID] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.active, job.ID)
+		delete(r.pausing, job.ID)
+		r.mu.Unlock()
+	}()
+
+	if err := r.handle(jobCtx, job); err != nil {
+		if job.NextRetryAt != nil {
+			log.Printf("job %s failed (attempt %d of %d), retrying at %s: %v", job.ID, job.Attempts, job.MaxAttempts, job.NextRetryAt.Format(time.RFC3339), err)
+			return
+		}
+		switch {
+		case errors.Is(context.Cause(jobCtx), ErrCancelled):
+			log.Printf("job %s cancelled", job.ID)
+		case ctx.Err() != nil:
+			log.Printf("job %s interrupted by shutdown", job.ID)
+		default:
+			log.Printf("job %s failed: %v", job.ID, err)
+		}
+	}
+}
 
-	if err := r.placeFiles(job); err != nil {
-		_ = r.store.UpdateJobState(job.ID, StatusFailed, PhaseFailed, err.Error(), job.Progress, true)
-		_ = r.store.AddJobLog(job.ID, fmt.Sprintf("Job failed: %v", err))
+// Cancel stops a running job through its context, or marks a queued job so
+// workers skip it. Finished jobs return ErrJobFinished.
+func (r *Runner) Cancel(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.active[id]; ok {
+		cancel(ErrCancelled)
+		return nil
+	}
+	job, err := r.store.GetJob(id)
+	if err != nil {
+		return err
+	}
+	// A running job that no worker owns was claimed but not started yet; it
+	// can be cancelled like a queued one.
+	switch {
+	case job == nil:
+		return ErrJobFinished
+	case job.Status == StatusQueued, job.Status == StatusRunning, job.Status == StatusPaused,
+		job.Status == StatusScheduled, job.Status == StatusWaitingWindow, job.Status == StatusWaitingDisk,
+		job.Status == StatusPlanned:
+	default:
+		return ErrJobFinished
+	}
+	if err := r.store.UpdateJobState(id, StatusCancelled, PhaseCancelled, "Cancelled before start", job.Progress, true); err != nil {
 		return err
 	}
+	if job.NextRetryAt != nil {
+		_ = r.store.UpdateJobAttempts(id, job.Attempts, nil)
+	}
+	// Jobs waiting for a retry or restart may still hold temp artifacts.
+	r.cancelItems(job)
+	_ = r.store.AddJobLog(id, "Cancelled before start")
+	return nil
+}
+
+// Retry re-queues a failed, partial or interrupted job with a fresh attempt
+// budget. Completed items are kept; the others resume after the last
+// checkpoint whose temp artifacts still exist. A *DuplicateError is returned
+// when another active job has taken over one of the unfinished albums since.
+func (r *Runner) Retry(id string) error {
+	r.submitMu.Lock()
+	defer r.submitMu.Unlock()
 
-	if err := r.store.UpdateJobState(job.ID, StatusRunning, PhaseCleanup, "Cleaning up temp files (stubbed)", 0.95, false); err != nil {
+	job, err := r.store.GetJob(id)
+	if err != nil {
+		return err
+	}
+	if job == nil || (job.Status != StatusFailed && job.Status != StatusPartial && job.Status != StatusInterrupted) {
+		return ErrNotRetryable
+	}
+	if err := r.checkRequeue(job); err != nil {
 		return err
 	}
-	_ = r.store.AddJobLog(job.ID, "Cleaning up temp files (stubbed)")
-	time.Sleep(150 * time.Millisecond)
+	pending := 0
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		if item.Status == StatusCompleted {
+			continue
+		}
+		r.rewindCheckpoint(job, item)
+		item.Status, item.Message = StatusQueued, "queued"
+		pending++
+	}
+	msg := fmt.Sprintf("Retry requested for %d of %d albums", pending, len(job.Items))
+	ok, err := r.store.RequeueJob(id, msg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Another request retried it first.
+		return ErrNotRetryable
+	}
+	_ = r.store.AddJobLog(id, msg)
+	r.Notify()
+	return nil
+}
+
+// retryDelay returns the backoff before the given automatic retry (1-based).
+func (r *Runner) retryDelay(attempt int) time.Duration {
+	delay := r.cfg.JobRetryBackoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// acquire takes a slot from pool; receiving from pool gives it back.
+func acquire(ctx context.Context, pool chan struct{}) error {
+	select {
+	case pool <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reached reports whether checkpoint is at or past phase.
+func reached(checkpoint, phase string) bool {
+	return phaseIndex(checkpoint) >= phaseIndex(phase)
+}
+
+func phaseIndex(phase string) int {
+	for idx, p := range phaseOrder {
+		if p == phase {
+			return idx
+		}
+	}
+	return -1
+}
+
+// tempPaths returns an item's archive and staging paths inside its job's workspace.
+func (r *Runner) tempPaths(job *store.Job, item *store.JobItem) (archive, staging string) {
+	name := sanitizeName(item.SourceID)
+	if name == "" || name == "." {
+		name = "item"
+	}
+	base := filepath.Join(r.workspace(job), name)
+	return base + ".zip", base
+}
+
+// checkpoint persists phase as completed for item so a restart can resume after it.
+func (r *Runner) checkpoint(job *store.Job, item *store.JobItem, phase string) error {
+	item.Checkpoint = phase
+	return r.store.UpdateJobItemCheckpoint(job.ID, item.SourceID, phase, item.SourceURL)
+}
+
+// progress records that item idx entered step and moves the job's progress to
+// the aggregate of its items: finished items count fully, the current one by
+// the weight of the steps it has passed.
+func (r *Runner) progress(job *store.Job, idx, step int) error {
+	item := &job.Items[idx]
+	info := r.steps[step].Info()
+	phase, msg := info.Phase, info.Message
+	// Items run in order, so earlier ones are finished either way.
+	done := idx
+	for i := idx + 1; i < len(job.Items); i++ {
+		if job.Items[i].Status == StatusCompleted {
+			done++
+		}
+	}
+	job.Phase = phase
+	job.Progress = (float64(done) + r.starts[step]) / float64(len(job.Items))
+	item.Status, item.Message = StatusRunning, msg
+	if len(job.Items) > 1 {
+		msg = fmt.Sprintf("%s: %s", itemLabel(item), msg)
+	}
+	_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusRunning, item.Message)
+	_ = r.store.AddJobLog(job.ID, msg)
+	return r.store.UpdateJobState(job.ID, StatusRunning, phase, msg, job.Progress, false)
+}
+
+// logItem appends a job log line, prefixed with the item in multi-album jobs.
+func (r *Runner) logItem(job *store.Job, item *store.JobItem, msg string) {
+	if len(job.Items) > 1 {
+		msg = fmt.Sprintf("%s: %s", itemLabel(item), msg)
+	}
+	_ = r.store.AddJobLog(job.ID, msg)
+}
+
+// handle runs every unfinished item of job through the pipeline in turn. An
+// item failing does not stop the others; the job ends completed, partial or
+// failed depending on how many items were placed.
+func (r *Runner) handle(ctx context.Context, job *store.Job) error {
+	if len(job.Items) == 0 {
+		return r.fail(ctx, job, errors.New("job has no items"))
+	}
+	if err := os.MkdirAll(r.workspace(job), 0755); err != nil {
+		return r.fail(ctx, job, fmt.Errorf("create workspace: %w", err))
+	}
+	// Artifacts may have been swept since the job last ran.
+	for idx := range job.Items {
+		if job.Items[idx].Status != StatusCompleted {
+			r.rewindCheckpoint(job, &job.Items[idx])
+		}
+	}
+	var errs []error
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		if item.Status == StatusCompleted {
+			continue
+		}
+		if item.Status == StatusFailed {
+			// Failed earlier in this attempt, before the job was parked.
+			errs = append(errs, &recordedFailure{msg: item.Message, code: job.ErrorCode})
+			continue
+		}
+		err := r.handleItem(ctx, job, idx)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, errPaused) {
+			r.recordFailures(job, errs)
+			return r.park(job, idx)
+		}
+		if errors.Is(err, errOutsideWindow) {
+			r.recordFailures(job, errs)
+			return r.waitForWindow(job, idx)
+		}
+		var diskErr *DiskSpaceError
+		if errors.As(err, &diskErr) {
+			r.recordFailures(job, errs)
+			return r.waitForDisk(job, idx, diskErr)
+		}
+		timedOut := errors.Is(context.Cause(ctx), errJobTimeout)
+		if timedOut {
+			err = &TimeoutError{Phase: job.Phase, Limit: r.cfg.JobTimeout, Job: true}
+		} else if ctx.Err() != nil {
+			return r.fail(ctx, job, err)
+		}
+		item.Status, item.Message = StatusFailed, err.Error()
+		_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusFailed, err.Error())
+		r.logItem(job, item, fmt.Sprintf("Failed: %v", err))
+		if timedOut {
+			// The remaining items cannot run without a live context.
+			return r.fail(ctx, job, err)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		err := errs[0]
+		if len(job.Items) > 1 {
+			err = fmt.Errorf("%d of %d albums failed; first error: %w", len(errs), len(job.Items), errs[0])
+		}
+		return r.fail(ctx, job, err)
+	}
 
+	job.Progress = 1.0
+	if job.ErrorCode != "" {
+		job.ErrorCode = ""
+		_ = r.store.UpdateJobErrorCode(job.ID, "")
+	}
 	if err := r.store.UpdateJobState(job.ID, StatusCompleted, PhaseCompleted, "Completed", 1.0, true); err != nil {
 		return err
 	}
 	_ = r.store.AddJobLog(job.ID, "Job completed")
+	r.removeWorkspace(job)
 	return nil
 }
 
-func (r *Runner) placeFiles(job *store.Job) error {
-	artist := sanitizeName(job.Artist)
-	album := sanitizeName(job.Album)
-	if artist == "" {
-		artist = "Unknown Artist"
+// recordedFailure is an item failure from before the job was parked; code is
+// the job error code stored when it parked.
+type recordedFailure struct {
+	msg  string
+	code string
+}
+
+func (e *recordedFailure) Error() string {
+	return e.msg
+}
+
+// recordFailures stores the error code of items that failed before job is
+// parked. The items themselves are already marked failed, so they are not run
+// again when the job resumes and still count towards this attempt.
+func (r *Runner) recordFailures(job *store.Job, errs []error) {
+	if len(errs) == 0 {
+		return
 	}
-	if album == "" {
-		album = "Unknown Album"
+	if code := errorCode(errs[0]); code != job.ErrorCode {
+		job.ErrorCode = code
+		_ = r.store.UpdateJobErrorCode(job.ID, code)
+	}
+}
+
+// handleItem runs one album through the pipeline, skipping steps its
+// checkpoint has already passed. Consecutive steps in the same slot pool keep
+// their slot, so a job extracting and placing is not overtaken in between.
+func (r *Runner) handleItem(ctx context.Context, job *store.Job, idx int) error {
+	item := &job.Items[idx]
+	it := r.stepItem(job, item)
+
+	// held is the pool whose slot this item holds, if any.
+	var held chan struct{}
+	defer func() {
+		if held != nil {
+			<-held
+		}
+	}()
+	for step, s := range r.steps {
+		info := s.Info()
+		if reached(item.Checkpoint, info.Phase) {
+			continue
+		}
+		if info.Slot == SlotNetwork {
+			if err := r.networkGate(job.ID); err != nil {
+				return err
+			}
+		} else if r.pauseRequested(job.ID) {
+			return errPaused
+		}
+		if p, ok := s.(Preflighter); ok {
+			if err := p.Preflight(ctx, it); err != nil {
+				return err
+			}
+		}
+		if pool := r.pool(info.Slot); pool != held {
+			if held != nil {
+				<-held
+				held = nil
+			}
+			if pool != nil {
+				if err := acquire(ctx, pool); err != nil {
+					return err
+				}
+				held = pool
+			}
+		}
+		if err := r.progress(job, idx, step); err != nil {
+			return err
+		}
+		if err := runPhase(ctx, info.Phase, info.Timeout, func(ctx context.Context) error { return s.Run(ctx, it) }); err != nil {
+			return err
+		}
+		if it.Skipped != item.Skipped {
+			// Stored before the checkpoint, so steps resumed after placing see it.
+			item.Skipped = it.Skipped
+			if err := r.store.UpdateJobItemSkipped(job.ID, item.SourceID, it.Skipped); err != nil {
+				return err
+			}
+		}
+		if err := r.checkpoint(job, item, info.Phase); err != nil {
+			return err
+		}
+	}
+	item.Status, item.Message = StatusCompleted, it.Summary
+	return r.store.UpdateJobItem(job.ID, item.SourceID, StatusCompleted, it.Summary)
+}
+
+// stepItem returns the state steps share while working on item.
+func (r *Runner) stepItem(job *store.Job, item *store.JobItem) *StepItem {
+	archive, staging := r.tempPaths(job, item)
+	return &StepItem{
+		Job:     job,
+		Item:    item,
+		Archive: archive,
+		Staging: staging,
+		Target:  r.targetDir(job, item),
+		// Placing decides again whenever the item has not got past it.
+		Skipped: item.Skipped && reached(item.Checkpoint, PhasePlacing),
+		Summary: "Completed",
+		log:     func(msg string) { r.logItem(job, item, msg) },
+	}
+}
+
+// pool returns the slot pool for slot, or nil for SlotNone.
+func (r *Runner) pool(slot Slot) chan struct{} {
+	switch slot {
+	case SlotNetwork:
+		return r.network
+	case SlotDisk:
+		return r.disk
 	}
+	return nil
+}
 
-	targetDir := filepath.Join(r.cfg.NavidromePath, artist, album)
-	if _, err := os.Stat(targetDir); err == nil {
-		msg := fmt.Sprintf("Album already exists at %s, skipping", targetDir)
+// recoverJobs puts jobs left running by a previous process back in the queue.
+// Jobs cut off while placing files are marked interrupted instead, since their
+// target folder may be half written. Queued jobs need nothing: they are still
+// in the table.
+func (r *Runner) recoverJobs() {
+	pending, err := r.store.ListUnfinishedJobs()
+	if err != nil {
+		log.Printf("recover jobs: %v", err)
+		return
+	}
+	recovered := 0
+	for idx := range pending {
+		job := &pending[idx]
+		if job.Status == StatusRunning {
+			current := runningItem(job)
+			if current != nil && job.Phase == PhasePlacing && current.Checkpoint != PhasePlacing {
+				// The album folder only appears once complete, so only the
+				// hidden partial placement needs clearing.
+				target := r.targetDir(job, current)
+				if err := os.RemoveAll(partialDir(target)); err != nil {
+					log.Printf("job %s: remove partial placement: %v", job.ID, err)
+				}
+				msg := fmt.Sprintf("Interrupted while placing files into %s; partial files removed, retry to place them again", target)
+				_ = r.store.UpdateJobItem(job.ID, current.SourceID, StatusInterrupted, msg)
+				_ = r.store.AddJobLog(job.ID, msg)
+				_ = r.store.UpdateJobState(job.ID, StatusInterrupted, PhaseFailed, msg, job.Progress, true)
+				log.Printf("job %s interrupted during placing", job.ID)
+				continue
+			}
+			for i := range job.Items {
+				if job.Items[i].Status != StatusCompleted {
+					r.rewindCheckpoint(job, &job.Items[i])
+				}
+			}
+			msg := "Restart interrupted the job; starting over"
+			if current != nil && current.Checkpoint != "" {
+				msg = fmt.Sprintf("Restart interrupted the job; resuming %s after %s", itemLabel(current), current.Checkpoint)
+			}
+			_ = r.store.AddJobLog(job.ID, msg)
+			_ = r.store.UpdateJobState(job.ID, StatusQueued, job.Phase, "Resuming after restart", job.Progress, false)
+			recovered++
+		}
+	}
+	if recovered > 0 {
+		log.Printf("recovered %d unfinished jobs", recovered)
+	}
+}
+
+// runningItem returns the item a job was working on, or nil.
+func runningItem(job *store.Job) *store.JobItem {
+	for idx := range job.Items {
+		if job.Items[idx].Status == StatusRunning {
+			return &job.Items[idx]
+		}
+	}
+	return nil
+}
+
+// rewindCheckpoint moves an item's checkpoint back to the last phase whose artifacts still exist.
+func (r *Runner) rewindCheckpoint(job *store.Job, item *store.JobItem) {
+	archive, staging := r.tempPaths(job, item)
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	checkpoint := item.Checkpoint
+	if checkpoint == PhaseExtracting && !exists(staging) {
+		checkpoint = PhaseDownloading
+	}
+	if checkpoint == PhaseDownloading && !exists(archive) {
+		checkpoint = PhaseFetchingSource
+	}
+	if checkpoint == PhaseFetchingSource && item.SourceURL == "" {
+		checkpoint = ""
+	}
+	if checkpoint != item.Checkpoint {
+		item.Checkpoint = checkpoint
+		_ = r.store.UpdateJobItemCheckpoint(job.ID, item.SourceID, checkpoint, item.SourceURL)
+	}
+}
+
+// cancelItems marks every unfinished item cancelled and removes the job's workspace.
+func (r *Runner) cancelItems(job *store.Job) {
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		if item.Status == StatusCompleted {
+			continue
+		}
+		item.Status, item.Message = StatusCancelled, "Cancelled"
+		_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusCancelled, "Cancelled")
+	}
+	r.removeWorkspace(job)
+}
+
+// fail records err on the job and returns it. Cancelled jobs are marked
+// cancelled; errors caused by the runner shutting down are only logged and
+// the job keeps its current state. Other failures, timeouts included, count
+// as an attempt and are re-queued with backoff (job.NextRetryAt) until
+// MaxAttempts is reached; the job then ends partial if any item was placed,
+// failed otherwise, and items that never got to run are cancelled with err as
+// the reason. The job's error code records what kind of failure it was.
+func (r *Runner) fail(ctx context.Context, job *store.Job, err error) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrCancelled) {
+		r.cancelItems(job)
+		_ = r.store.UpdateJobState(job.ID, StatusCancelled, PhaseCancelled, "Cancelled", job.Progress, true)
+		_ = r.store.AddJobLog(job.ID, "Cancelled by user; temp files removed")
+		return err
+	}
+	if ctx.Err() != nil && !errors.Is(cause, errJobTimeout) {
+		_ = r.store.AddJobLog(job.ID, "Interrupted by shutdown; will resume on restart")
+		return err
+	}
+	if code := errorCode(err); code != job.ErrorCode {
+		job.ErrorCode = code
+		_ = r.store.UpdateJobErrorCode(job.ID, code)
+	}
+	job.Attempts++
+	if job.Attempts < job.MaxAttempts {
+		delay := r.retryDelay(job.Attempts)
+		next := time.Now().Add(delay)
+		job.NextRetryAt = &next
+		msg := fmt.Sprintf("Attempt %d of %d failed: %v; retrying in %s", job.Attempts, job.MaxAttempts, err, delay)
+		// The next attempt runs failed items again.
+		for idx := range job.Items {
+			if item := &job.Items[idx]; item.Status == StatusFailed {
+				item.Status = StatusQueued
+				_ = r.store.UpdateJobItem(job.ID, item.SourceID, StatusQueued, item.Message)
+			}
+		}
+		_ = r.store.UpdateJobAttempts(job.ID, job.Attempts, &next)
+		_ = r.store.UpdateJobState(job.ID, StatusQueued, PhaseRetryWait, msg, job.Progress, false)
 		_ = r.store.AddJobLog(job.ID, msg)
-		_ = r.store.UpdateJobState(job.ID, StatusCompleted, PhaseCompleted, msg, 1.0, true)
+		return err
+	}
+	status, phase := StatusFailed, PhaseFailed
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		switch item.Status {
+		case StatusCompleted:
+			status, phase = StatusPartial, PhaseCompleted
+		case StatusFailed, StatusCancelled:
+		default:
+			item.Status, item.Message = StatusCancelled, fmt.Sprintf("Not run: %v", err)
+			_ = r.store.UpdateJobItem(job.ID, item.SourceID, item.Status, item.Message)
+		}
+	}
+	_ = r.store.UpdateJobAttempts(job.ID, job.Attempts, nil)
+	_ = r.store.UpdateJobState(job.ID, status, phase, err.Error(), job.Progress, true)
+	_ = r.store.AddJobLog(job.ID, fmt.Sprintf("Job %s: %v", status, err))
+	r.retainWorkspace(job)
+	return err
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
 		return nil
 	}
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("create target dir: %w", err)
+}
+
+func sourceRequest(job *store.Job, item *store.JobItem) source.Request {
+	req := source.Request{SourceID: item.SourceID, Artist: item.Artist, Album: item.Album}
+	if req.Artist == "" {
+		req.Artist = job.Artist
+	}
+	if req.Album == "" {
+		req.Album = item.Title
 	}
+	return req
+}
 
-	if err := r.store.UpdateJobState(job.ID, StatusRunning, PhasePlacing, "Placing files into Navidrome path (stubbed)", 0.7, false); err != nil {
-		return err
+// albumFolder returns the sanitized artist and album folder names for an item.
+func albumFolder(job *store.Job, item *store.JobItem) (artist, album string) {
+	req := sourceRequest(job, item)
+	artist = sanitizeName(req.Artist)
+	album = sanitizeName(req.Album)
+	if artist == "" {
+		artist = "Unknown Artist"
+	}
+	if album == "" {
+		album = "Unknown Album"
 	}
-	_ = r.store.AddJobLog(job.ID, fmt.Sprintf("Writing placeholder files to %s", targetDir))
+	return artist, album
+}
 
-	placeholder := filepath.Join(targetDir, "IMPORT_README.txt")
-	content := fmt.Sprintf("Placeholder import for job %s\nArtist: %s\nAlbum: %s\nThis is a stub; wire actual download/extract logic.", job.ID, artist, album)
-	if err := os.WriteFile(placeholder, []byte(content), 0644); err != nil {
-		return fmt.Errorf("write placeholder: %w", err)
+func (r *Runner) targetDir(job *store.Job, item *store.JobItem) string {
+	artist, album := albumFolder(job, item)
+	return filepath.Join(r.cfg.NavidromePath, artist, album)
+}
+
+// itemLabel names an item in log lines and job messages.
+func itemLabel(item *store.JobItem) string {
+	album := item.Album
+	if album == "" {
+		album = item.Title
 	}
-	return nil
+	if item.Artist == "" {
+		return album
+	}
+	return item.Artist + " - " + album
 }
 
 func sanitizeName(name string) string {
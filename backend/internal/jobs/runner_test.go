@@ -0,0 +1,461 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/store"
+)
+
+// funcStep runs fn as the step for phase, counting runs per item.
+type funcStep struct {
+	phase     string
+	fn        func(ctx context.Context, it *StepItem) error
+	preflight func(it *StepItem) error
+	runs      map[string]int
+}
+
+func (s funcStep) Info() StepInfo {
+	return StepInfo{Phase: s.phase, Message: s.phase, Weight: 1}
+}
+
+func (s funcStep) Run(ctx context.Context, it *StepItem) error {
+	s.runs[it.Item.SourceID]++
+	if s.fn == nil {
+		return nil
+	}
+	return s.fn(ctx, it)
+}
+
+func (s funcStep) Preflight(ctx context.Context, it *StepItem) error {
+	if s.preflight == nil {
+		return nil
+	}
+	return s.preflight(it)
+}
+
+func TestHandleKeepsFailuresAcrossParking(t *testing.T) {
+	diskFull := true
+	resolve := funcStep{phase: PhaseFetchingSource, runs: map[string]int{}, fn: func(ctx context.Context, it *StepItem) error {
+		if it.Item.SourceID == "a" {
+			return errors.New("no source for a")
+		}
+		return nil
+	}}
+	download := funcStep{phase: PhaseDownloading, runs: map[string]int{}, preflight: func(it *StepItem) error {
+		if diskFull {
+			return &DiskSpaceError{Path: "/tmp", Free: 1, Need: 2}
+		}
+		return nil
+	}}
+	r := newTestRunner(t, config.Config{JobRetryBackoff: 1})
+	r.steps = []Step{resolve, download}
+	r.starts = stepStarts(r.steps)
+
+	id := uuid.NewString()
+	err := r.store.InsertJob(&store.Job{ID: id, Status: StatusRunning, Phase: PhaseQueued, MaxAttempts: 2, Items: []store.JobItem{
+		{SourceID: "a", SourceType: "album", Status: StatusQueued},
+		{SourceID: "b", SourceType: "album", Status: StatusQueued},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func() *store.Job {
+		job, err := r.store.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+
+	// a fails, then b waits for disk space.
+	if err := r.handle(context.Background(), load()); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	job := load()
+	if job.Status != StatusWaitingDisk || job.Items[0].Status != StatusFailed || job.Attempts != 0 {
+		t.Fatalf("parked job = %s, item a %s, attempts %d", job.Status, job.Items[0].Status, job.Attempts)
+	}
+
+	// Once there is room b completes; a is not run again, but its failure
+	// still costs this attempt.
+	diskFull = false
+	if err := r.handle(context.Background(), job); err == nil {
+		t.Fatal("handle succeeded despite a failed item")
+	}
+	if resolve.runs["a"] != 1 || download.runs["b"] != 1 {
+		t.Errorf("runs = %v, %v", resolve.runs, download.runs)
+	}
+	job = load()
+	if job.Attempts != 1 || job.Phase != PhaseRetryWait {
+		t.Errorf("attempts = %d, phase = %s; want 1 and a retry", job.Attempts, job.Phase)
+	}
+	if job.Items[0].Status != StatusQueued || job.Items[1].Status != StatusCompleted {
+		t.Errorf("items = %s, %s; the retry should run a again", job.Items[0].Status, job.Items[1].Status)
+	}
+}
+
+func TestFailCancelsItemsThatNeverRan(t *testing.T) {
+	// b runs until the job deadline, so c never gets to run.
+	step := funcStep{phase: PhaseFetchingSource, runs: map[string]int{}, fn: func(ctx context.Context, it *StepItem) error {
+		if it.Item.SourceID == "b" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	r := newTestRunner(t, config.Config{JobTimeout: 50 * time.Millisecond})
+	r.steps = []Step{step}
+	r.starts = stepStarts(r.steps)
+
+	job := &store.Job{ID: uuid.NewString(), Status: StatusQueued, Phase: PhaseQueued, MaxAttempts: 1, Items: []store.JobItem{
+		{SourceID: "a", SourceType: "album", Status: StatusQueued},
+		{SourceID: "b", SourceType: "album", Status: StatusQueued},
+		{SourceID: "c", SourceType: "album", Status: StatusQueued},
+	}}
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+	r.run(context.Background(), job)
+
+	got, err := r.store.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusPartial {
+		t.Fatalf("status = %s, want partial", got.Status)
+	}
+	want := []string{StatusCompleted, StatusFailed, StatusCancelled}
+	for idx, item := range got.Items {
+		if item.Status != want[idx] {
+			t.Errorf("item %s = %s, want %s", item.SourceID, item.Status, want[idx])
+		}
+	}
+	if msg := got.Items[2].Message; !strings.HasPrefix(msg, "Not run: ") || !strings.Contains(msg, "timed out") {
+		t.Errorf("message of the item that never ran = %q", msg)
+	}
+	if step.runs["c"] != 0 {
+		t.Errorf("c ran %d times", step.runs["c"])
+	}
+}
+
+func TestRetryAfterCrashWhilePlacing(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	r.steps = []Step{placeStep{}, cleanupStep{}}
+	r.starts = stepStarts(r.steps)
+	job := &store.Job{ID: uuid.NewString(), Status: StatusRunning, Phase: PhasePlacing, Artist: "Pulse Runner", MaxAttempts: 1,
+		Items: []store.JobItem{{SourceID: "alb1", SourceType: "album", Title: "Cities in Motion", Status: StatusRunning}}}
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+	item := &job.Items[0]
+	target := r.targetDir(job, item)
+
+	// The previous process had extracted the album and was placing it.
+	_, staging := r.tempPaths(job, item)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"01 Intro.flac", "02 Outro.flac"} {
+		if err := os.WriteFile(filepath.Join(staging, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(partialDir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(partialDir(target), "01 Intro.flac"), []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.store.UpdateJobItemCheckpoint(job.ID, item.SourceID, PhaseExtracting, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	r.recoverJobs()
+	loaded, _ := r.store.GetJob(job.ID)
+	if loaded.Status != StatusInterrupted {
+		t.Fatalf("status after recovery = %s", loaded.Status)
+	}
+	if exists(partialDir(target)) || exists(target) {
+		t.Fatal("recovery left a partial album behind")
+	}
+
+	if err := r.Retry(job.ID); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	loaded, _ = r.store.GetJob(job.ID)
+	if err := r.handle(context.Background(), loaded); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if got := listDir(t, target); strings.Join(got, ",") != "01 Intro.flac,02 Outro.flac" {
+		t.Errorf("placed files = %v", got)
+	}
+	if loaded.Items[0].Skipped {
+		t.Error("the retried album was taken for an existing one")
+	}
+}
+
+func TestPlaceStepReplacesStalePartialFolder(t *testing.T) {
+	it := newStepItem(t)
+	writeZip(t, it.Archive, map[string]string{"01 Intro.flac": "a"})
+	if _, err := extractZip(context.Background(), it.Archive, it.Staging); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(partialDir(it.Target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(partialDir(it.Target), "01 Intro.flac"), []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (placeStep{}).Run(context.Background(), it); err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	if body, _ := os.ReadFile(filepath.Join(it.Target, "01 Intro.flac")); string(body) != "a" || it.Skipped {
+		t.Errorf("placed %q, skipped %v", body, it.Skipped)
+	}
+	if exists(partialDir(it.Target)) {
+		t.Error("partial folder left behind")
+	}
+}
+
+func TestRequeueRejectsDuplicates(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	failed := insertJob(t, r, StatusFailed, true)
+	paused := insertJob(t, r, StatusPaused, false)
+	other := insertJob(t, r, StatusQueued, false)
+
+	var dup *DuplicateError
+	if err := r.Retry(failed.ID); !errors.As(err, &dup) {
+		t.Fatalf("Retry = %v, want a DuplicateError", err)
+	}
+	if len(dup.Duplicates) != 1 || dup.Duplicates[0].SourceID != "alb1" {
+		t.Fatalf("duplicates = %+v", dup.Duplicates)
+	}
+	// Either way the job is left as it was.
+	if status, _ := r.store.GetJobStatus(failed.ID); status != StatusFailed {
+		t.Fatalf("status after rejected retry = %q", status)
+	}
+	if err := r.ResumeJob(paused.ID); !errors.As(err, &dup) {
+		t.Fatalf("ResumeJob = %v, want a DuplicateError", err)
+	}
+	if status, _ := r.store.GetJobStatus(paused.ID); status != StatusPaused {
+		t.Fatalf("status after rejected resume = %q", status)
+	}
+
+	if err := r.store.UpdateJobState(other.ID, StatusCompleted, PhaseCompleted, "done", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ResumeJob(paused.ID); err != nil {
+		t.Fatalf("ResumeJob once the other job finished = %v", err)
+	}
+	// The resumed job now covers the album, so the failed one still cannot retry.
+	if err := r.Retry(failed.ID); !errors.As(err, &dup) || dup.Duplicates[0].JobID != paused.ID {
+		t.Fatalf("Retry = %v, want it covered by %s", err, paused.ID)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		status   string
+		finished bool
+		wantErr  error
+	}{
+		{status: StatusQueued},
+		{status: StatusScheduled},
+		{status: StatusPaused},
+		{status: StatusWaitingDisk},
+		{status: StatusPlanned},
+		{status: StatusCompleted, finished: true, wantErr: ErrJobFinished},
+		{status: StatusFailed, finished: true, wantErr: ErrJobFinished},
+	}
+	r := newTestRunner(t, config.Config{})
+	for _, tt := range tests {
+		job := insertJob(t, r, tt.status, tt.finished)
+		err := r.Cancel(job.ID)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Cancel(%s) = %v, want %v", tt.status, err, tt.wantErr)
+			continue
+		}
+		got, err := r.store.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.wantErr != nil {
+			if got.Status != tt.status {
+				t.Errorf("Cancel(%s) changed the status to %s", tt.status, got.Status)
+			}
+			continue
+		}
+		if got.Status != StatusCancelled || got.Items[0].Status != StatusCancelled {
+			t.Errorf("Cancel(%s): job %s, item %s; want both cancelled", tt.status, got.Status, got.Items[0].Status)
+		}
+		if exists(r.workspace(job)) {
+			t.Errorf("Cancel(%s) kept the workspace", tt.status)
+		}
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	step := funcStep{phase: PhaseDownloading, runs: map[string]int{}, fn: func(ctx context.Context, it *StepItem) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	r := newTestRunner(t, config.Config{})
+	r.steps = []Step{step}
+	r.starts = stepStarts(r.steps)
+	job := insertJob(t, r, StatusRunning, false)
+
+	done := make(chan struct{})
+	go func() {
+		r.run(context.Background(), job)
+		close(done)
+	}()
+	<-started
+	if err := r.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job kept running after Cancel")
+	}
+
+	got, err := r.store.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusCancelled || got.Items[0].Status != StatusCancelled || got.Attempts != 0 {
+		t.Errorf("job %s, item %s, attempts %d; want cancelled without using an attempt", got.Status, got.Items[0].Status, got.Attempts)
+	}
+	if exists(r.workspace(job)) {
+		t.Error("cancelled job kept its workspace")
+	}
+	if err := r.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("second Cancel = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{status: StatusFailed},
+		{status: StatusPartial},
+		{status: StatusInterrupted},
+		{status: StatusCompleted, wantErr: ErrNotRetryable},
+		{status: StatusCancelled, wantErr: ErrNotRetryable},
+		{status: StatusQueued, wantErr: ErrNotRetryable},
+	}
+	step := funcStep{phase: PhaseFetchingSource, runs: map[string]int{}}
+	r := newTestRunner(t, config.Config{})
+	r.steps = []Step{step}
+	r.starts = stepStarts(r.steps)
+	for _, tt := range tests {
+		done, left := tt.status+"-done", tt.status+"-left"
+		job := &store.Job{ID: uuid.NewString(), Status: tt.status, Phase: tt.status, MaxAttempts: 3, Items: []store.JobItem{
+			{SourceID: done, SourceType: "album", Status: StatusCompleted},
+			{SourceID: left, SourceType: "album", Status: StatusFailed},
+		}}
+		if err := r.store.InsertJob(job); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.store.UpdateJobAttempts(job.ID, 3, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Retry(job.ID); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Retry(%s) = %v, want %v", tt.status, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		got, err := r.store.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != StatusQueued || got.Attempts != 0 || got.Items[0].Status != StatusCompleted || got.Items[1].Status != StatusQueued {
+			t.Errorf("Retry(%s): job %s with %d attempts, items %s/%s", tt.status, got.Status, got.Attempts, got.Items[0].Status, got.Items[1].Status)
+			continue
+		}
+
+		// The retried job is claimed again and only runs the unfinished item.
+		claimed, err := r.store.ClaimJob(time.Now(), r.claimable(time.Now())...)
+		if err != nil || claimed == nil || claimed.ID != job.ID {
+			t.Fatalf("ClaimJob after Retry(%s) = %v, %v", tt.status, claimed, err)
+		}
+		r.run(context.Background(), claimed)
+		if status, _ := r.store.GetJobStatus(job.ID); status != StatusCompleted {
+			t.Errorf("retried %s job ended %s", tt.status, status)
+		}
+		if step.runs[done] != 0 || step.runs[left] != 1 {
+			t.Errorf("retried %s job ran %v", tt.status, step.runs)
+		}
+	}
+}
+
+func TestJobTimeoutCostsOneAttempt(t *testing.T) {
+	step := funcStep{phase: PhaseDownloading, runs: map[string]int{}, fn: func(ctx context.Context, it *StepItem) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	r := newTestRunner(t, config.Config{JobTimeout: 30 * time.Millisecond, JobRetryBackoff: time.Hour})
+	r.steps = []Step{step}
+	r.starts = stepStarts(r.steps)
+	job := &store.Job{ID: uuid.NewString(), Status: StatusQueued, Phase: PhaseQueued, MaxAttempts: 2, Items: []store.JobItem{
+		{SourceID: "a", SourceType: "album", Status: StatusQueued},
+		{SourceID: "b", SourceType: "album", Status: StatusQueued},
+	}}
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status, phase string
+		attempts      int
+		retrying      bool
+		items         []string
+	}{
+		// The first timeout is retried; both items run again.
+		{StatusQueued, PhaseRetryWait, 1, true, []string{StatusQueued, StatusQueued}},
+		// The second uses up the attempts; b never ran.
+		{StatusFailed, PhaseFailed, 2, false, []string{StatusFailed, StatusCancelled}},
+	}
+	for run, tt := range tests {
+		loaded, err := r.store.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.run(context.Background(), loaded)
+
+		got, err := r.store.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != tt.status || got.Phase != tt.phase || got.Attempts != tt.attempts || got.ErrorCode != ErrorCodeTimeout {
+			t.Errorf("run %d: %s/%s, %d attempts, error code %q; want %s/%s, %d attempts, %q",
+				run+1, got.Status, got.Phase, got.Attempts, got.ErrorCode, tt.status, tt.phase, tt.attempts, ErrorCodeTimeout)
+		}
+		if (got.NextRetryAt != nil) != tt.retrying {
+			t.Errorf("run %d: nextRetryAt = %v", run+1, got.NextRetryAt)
+		}
+		for idx, item := range got.Items {
+			if item.Status != tt.items[idx] {
+				t.Errorf("run %d: item %s = %s, want %s", run+1, item.SourceID, item.Status, tt.items[idx])
+			}
+		}
+	}
+	if step.runs["a"] != 2 || step.runs["b"] != 0 {
+		t.Errorf("runs = %v, want a twice and b never", step.runs)
+	}
+}
@@ -0,0 +1,340 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"navidrome-helper/internal/library"
+	"navidrome-helper/internal/source"
+)
+
+// resolveStep asks the resolver for the album's archive link.
+type resolveStep struct {
+	resolver source.Resolver
+	timeout  time.Duration
+}
+
+func (s resolveStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseFetchingSource, Message: "Fetching pixeldrain link via doubledouble.top", Weight: 1, Slot: SlotNetwork, Timeout: s.timeout}
+}
+
+func (s resolveStep) Run(ctx context.Context, it *StepItem) error {
+	link, err := s.resolve(ctx, it)
+	if err != nil {
+		return err
+	}
+	it.Logf("Resolved %s", link.URL)
+	it.Item.SourceURL = link.URL
+	it.Item.EstimatedSize = link.Size
+	return nil
+}
+
+func (s resolveStep) Plan(ctx context.Context, it *StepItem) error {
+	link, err := s.resolve(ctx, it)
+	if err != nil {
+		return err
+	}
+	it.Item.SourceURL = link.URL
+	it.Item.EstimatedSize = link.Size
+	return nil
+}
+
+func (s resolveStep) resolve(ctx context.Context, it *StepItem) (*source.Link, error) {
+	link, err := s.resolver.Resolve(ctx, sourceRequest(it.Job, it.Item))
+	if err != nil {
+		return nil, fmt.Errorf("resolve source: %w", err)
+	}
+	return link, nil
+}
+
+// downloadStep fetches the resolved archive into the item's temp path.
+type downloadStep struct {
+	downloader source.Downloader
+	disk       diskCheck
+	timeout    time.Duration
+	// probeTimeout bounds asking for the archive size before downloading.
+	probeTimeout time.Duration
+}
+
+func (s downloadStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseDownloading, Message: "Downloading zip", Weight: 4, Slot: SlotNetwork, Timeout: s.timeout}
+}
+
+func (s downloadStep) Run(ctx context.Context, it *StepItem) error {
+	n, err := s.downloader.Download(ctx, &source.Link{URL: it.Item.SourceURL}, it.Archive)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	if n > 0 {
+		it.Logf("Downloaded %d bytes", n)
+	}
+	return nil
+}
+
+// Preflight makes sure TEMP_DIR has room for the archive and its extracted
+// copy, and NAVIDROME_MUSIC_PATH for the placed files; a filesystem holding
+// both needs room for all three. An unknown size only checks the reserve.
+func (s downloadStep) Preflight(ctx context.Context, it *StepItem) error {
+	if it.Item.EstimatedSize == 0 {
+		// Best effort: without a size the reserve still applies.
+		_ = runPhase(ctx, PhaseDownloading, s.probeTimeout, func(ctx context.Context) error { return s.Plan(ctx, it) })
+	}
+	size := uint64(max(it.Item.EstimatedSize, 0))
+	return s.disk.require(2*size, size)
+}
+
+// Plan asks the downloader for the archive size when the resolver did not report it.
+func (s downloadStep) Plan(ctx context.Context, it *StepItem) error {
+	sizer, ok := s.downloader.(source.Sizer)
+	if !ok || it.Item.SourceURL == "" || it.Item.EstimatedSize > 0 {
+		return nil
+	}
+	n, err := sizer.Size(ctx, &source.Link{URL: it.Item.SourceURL})
+	if err != nil {
+		return fmt.Errorf("estimate size: %w", err)
+	}
+	it.Item.EstimatedSize = n
+	return nil
+}
+
+// extractStep unpacks the archive into the staging dir. Stubbed downloads
+// leave no archive, so there is nothing to extract.
+type extractStep struct {
+	disk    diskCheck
+	timeout time.Duration
+}
+
+func (s extractStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseExtracting, Message: "Extracting archive", Weight: 2, Slot: SlotDisk, Timeout: s.timeout}
+}
+
+// Preflight makes sure the downloaded archive can be extracted and placed.
+// It matters for items resumed after downloading, whose download was checked
+// against an older free-space reading.
+func (s extractStep) Preflight(ctx context.Context, it *StepItem) error {
+	size := fileSize(it.Archive)
+	return s.disk.require(size, size)
+}
+
+func (s extractStep) Run(ctx context.Context, it *StepItem) error {
+	if _, err := os.Stat(it.Archive); err != nil {
+		return sleepCtx(ctx, 300*time.Millisecond)
+	}
+	// Clear leftovers of an earlier failed extraction.
+	_ = os.RemoveAll(it.Staging)
+	count, err := extractZip(ctx, it.Archive, it.Staging)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	it.Logf("Extracted %d files", count)
+	return nil
+}
+
+// placeStep moves the extracted files into NAVIDROME_MUSIC_PATH/<Artist>/<Album>.
+// Without extracted files (stubbed downloads) a placeholder README is written
+// instead. An existing album folder is left alone; the folder only appears
+// once every file is in it.
+type placeStep struct {
+	timeout time.Duration
+}
+
+func (s placeStep) Info() StepInfo {
+	return StepInfo{Phase: PhasePlacing, Message: "Placing files into Navidrome path", Weight: 2, Slot: SlotDisk, Timeout: s.timeout}
+}
+
+func (s placeStep) Run(ctx context.Context, it *StepItem) error {
+	if _, err := os.Stat(it.Target); err == nil {
+		it.Skipped = true
+		it.Summary = fmt.Sprintf("Album already exists at %s, skipping", it.Target)
+		it.Logf("%s", it.Summary)
+		return nil
+	}
+	it.Skipped = false
+
+	// Files are placed in a hidden sibling that is renamed into place once
+	// complete, so an interrupted placement never leaves a half-filled folder
+	// that a retry would take for an existing album.
+	partial := partialDir(it.Target)
+	if err := os.RemoveAll(partial); err != nil {
+		return fmt.Errorf("clear partial placement: %w", err)
+	}
+	if err := os.MkdirAll(partial, 0755); err != nil {
+		return fmt.Errorf("create target dir: %w", err)
+	}
+	if err := s.fill(ctx, it, partial); err != nil {
+		_ = os.RemoveAll(partial)
+		return err
+	}
+	if err := os.Rename(partial, it.Target); err != nil {
+		_ = os.RemoveAll(partial)
+		return fmt.Errorf("move album into place: %w", err)
+	}
+	return nil
+}
+
+// fill moves the extracted files into dir, or writes the placeholder README
+// when there are none.
+func (s placeStep) fill(ctx context.Context, it *StepItem, dir string) error {
+	if _, err := os.Stat(it.Staging); err == nil {
+		it.Logf("Moving extracted files to %s", it.Target)
+		count, err := moveAudioFiles(ctx, it.Staging, dir)
+		if err != nil {
+			return fmt.Errorf("place files: %w", err)
+		}
+		it.Summary = fmt.Sprintf("Placed %d files", count)
+		it.Logf("%s", it.Summary)
+		return nil
+	}
+
+	it.Logf("Writing placeholder files to %s", it.Target)
+	artist, album := albumFolder(it.Job, it.Item)
+	placeholder := filepath.Join(dir, "IMPORT_README.txt")
+	content := fmt.Sprintf("Placeholder import for job %s\nArtist: %s\nAlbum: %s\nThis is a stub; set ENABLE_DOWNLOADS=true for real downloads.", it.Job.ID, artist, album)
+	if err := os.WriteFile(placeholder, []byte(content), 0644); err != nil {
+		return fmt.Errorf("write placeholder: %w", err)
+	}
+	it.Summary = "Wrote placeholder"
+	return nil
+}
+
+// partialDir is where target is assembled before it is renamed into place.
+// The leading dot keeps Navidrome from scanning it.
+func partialDir(target string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".partial")
+}
+
+// Plan records the album folder and whether it already exists.
+func (s placeStep) Plan(ctx context.Context, it *StepItem) error {
+	it.Item.TargetPath = it.Target
+	if _, err := os.Stat(it.Target); err == nil {
+		it.Item.Conflict = ConflictExists
+	}
+	return nil
+}
+
+// coverArtStep downloads the item's cover URL into the album folder when the
+// archive brought no artwork. Covers are small, so the step takes no network
+// slot and ignores DOWNLOAD_WINDOW.
+type coverArtStep struct {
+	downloader source.Downloader
+	timeout    time.Duration
+}
+
+func (s coverArtStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseCoverArt, Message: "Fetching cover art", Weight: 0.5, Timeout: s.timeout}
+}
+
+func (s coverArtStep) Run(ctx context.Context, it *StepItem) error {
+	if it.Skipped || it.Item.CoverURL == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(it.Target)
+	if err != nil {
+		return fmt.Errorf("read album folder: %w", err)
+	}
+	for _, e := range entries {
+		if _, ok := coverNames[strings.ToLower(e.Name())]; ok {
+			return nil
+		}
+	}
+	name := "cover.jpg"
+	if u, err := url.Parse(it.Item.CoverURL); err == nil && strings.EqualFold(path.Ext(u.Path), ".png") {
+		name = "cover.png"
+	}
+	n, err := s.downloader.Download(ctx, &source.Link{URL: it.Item.CoverURL}, filepath.Join(it.Target, name))
+	if err != nil {
+		return fmt.Errorf("cover art: %w", err)
+	}
+	if n > 0 {
+		it.Logf("Saved cover art as %s", name)
+	}
+	return nil
+}
+
+// refreshStep rescans the library so search results see the new album.
+type refreshStep struct {
+	indexer *library.Indexer
+}
+
+func (s refreshStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseRefreshLibrary, Message: "Refreshing library index", Weight: 0.5}
+}
+
+func (s refreshStep) Run(ctx context.Context, it *StepItem) error {
+	if _, err := s.indexer.Refresh(ctx); err != nil {
+		return fmt.Errorf("refresh library: %w", err)
+	}
+	return nil
+}
+
+// hookStep runs POST_IMPORT_HOOK through sh after an album was placed. The
+// album is passed in NH_JOB_ID, NH_ARTIST, NH_ALBUM and NH_ALBUM_PATH.
+type hookStep struct {
+	command string
+	timeout time.Duration
+}
+
+func (s hookStep) Info() StepInfo {
+	return StepInfo{Phase: PhasePostImportHook, Message: "Running post-import hook", Weight: 0.5, Timeout: s.timeout}
+}
+
+func (s hookStep) Run(ctx context.Context, it *StepItem) error {
+	if it.Skipped {
+		return nil
+	}
+	artist, album := albumFolder(it.Job, it.Item)
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Env = append(os.Environ(),
+		"NH_JOB_ID="+it.Job.ID,
+		"NH_ARTIST="+artist,
+		"NH_ALBUM="+album,
+		"NH_ALBUM_PATH="+it.Target,
+	)
+	// Do not wait on output pipes held open by children after a kill.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("post-import hook: %w: %s", err, lastLine(msg))
+		}
+		return fmt.Errorf("post-import hook: %w", err)
+	}
+	return nil
+}
+
+// cleanupStep removes the item's temp archive and staging dir.
+type cleanupStep struct{}
+
+func (cleanupStep) Info() StepInfo {
+	return StepInfo{Phase: PhaseCleanup, Message: "Cleaning up temp files", Weight: 0.5}
+}
+
+// Run never fails the item: the album is in place by now, and leftovers are
+// removed with the job's workspace or by the sweeper. Failures are logged.
+func (cleanupStep) Run(ctx context.Context, it *StepItem) error {
+	if err := os.Remove(it.Archive); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("job %s: remove archive: %v", it.Job.ID, err)
+	}
+	if err := os.RemoveAll(it.Staging); err != nil {
+		log.Printf("job %s: remove staging dir: %v", it.Job.ID, err)
+	}
+	return nil
+}
+
+// lastLine returns the final line of multi-line command output.
+func lastLine(s string) string {
+	if idx := strings.LastIndexByte(s, '\n'); idx >= 0 {
+		return s[idx+1:]
+	}
+	return s
+}
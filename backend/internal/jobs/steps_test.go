@@ -0,0 +1,412 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/source"
+	"navidrome-helper/internal/store"
+)
+
+// sizedResolver resolves every request to url, reporting size.
+type sizedResolver struct {
+	url  string
+	size int64
+}
+
+func (r sizedResolver) Resolve(ctx context.Context, req source.Request) (*source.Link, error) {
+	return &source.Link{URL: r.url + req.SourceID, Size: r.size}, nil
+}
+
+// fileDownloader writes body to dest and can report a size.
+type fileDownloader struct {
+	body    string
+	size    int64
+	sizeErr error
+	sized   int
+}
+
+func (d *fileDownloader) Download(ctx context.Context, link *source.Link, dest string) (int64, error) {
+	if err := os.WriteFile(dest, []byte(d.body), 0644); err != nil {
+		return 0, err
+	}
+	return int64(len(d.body)), nil
+}
+
+func (d *fileDownloader) Size(ctx context.Context, link *source.Link) (int64, error) {
+	d.sized++
+	return d.size, d.sizeErr
+}
+
+// newStepItem returns an item whose temp paths and album folder live under t.TempDir().
+func newStepItem(t *testing.T) *StepItem {
+	t.Helper()
+	dir := t.TempDir()
+	job := &store.Job{ID: "job1", Artist: "Pulse Runner"}
+	item := &store.JobItem{SourceID: "alb1", Title: "Cities in Motion"}
+	return &StepItem{
+		Job:     job,
+		Item:    item,
+		Archive: filepath.Join(dir, "tmp", "alb1.zip"),
+		Staging: filepath.Join(dir, "tmp", "alb1"),
+		Target:  filepath.Join(dir, "music", "Pulse Runner", "Cities in Motion"),
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestResolveStepKeepsLinkAndSize(t *testing.T) {
+	it := newStepItem(t)
+	step := resolveStep{resolver: sizedResolver{url: "http://dl/", size: 1234}}
+	if err := step.Run(context.Background(), it); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if it.Item.SourceURL != "http://dl/alb1" || it.Item.EstimatedSize != 1234 {
+		t.Errorf("item = %+v", it.Item)
+	}
+}
+
+func TestDownloadStepPreflight(t *testing.T) {
+	free := map[string]uint64{"/tmp": 10_000, "/music": 10_000}
+	disk := diskCheck{tempDir: "/tmp", musicDir: "/music", reserve: 1000,
+		free: func(path string) (uint64, error) { return free[path], nil }}
+	dl := &fileDownloader{size: 4000}
+	step := downloadStep{downloader: dl, disk: disk, probeTimeout: time.Second}
+
+	it := newStepItem(t)
+	it.Item.SourceURL = "http://dl/alb1"
+	// 2×4000 for the archive and its extracted copy plus the reserve fits.
+	if err := step.Preflight(context.Background(), it); err != nil {
+		t.Fatalf("Preflight: %v", err)
+	}
+	if dl.sized != 1 || it.Item.EstimatedSize != 4000 {
+		t.Fatalf("size probe: sized=%d size=%d", dl.sized, it.Item.EstimatedSize)
+	}
+
+	free["/tmp"] = 8000
+	err := step.Preflight(context.Background(), it)
+	var diskErr *DiskSpaceError
+	if !errors.As(err, &diskErr) || diskErr.Path != "/tmp" || diskErr.Need != 9000 {
+		t.Errorf("Preflight error = %v, want /tmp needing 9000", err)
+	}
+	if dl.sized != 1 {
+		t.Errorf("known sizes should not be probed again")
+	}
+
+	// A failing probe leaves only the reserve to check.
+	it = newStepItem(t)
+	it.Item.SourceURL = "http://dl/alb1"
+	dl.sizeErr = errors.New("no HEAD")
+	if err := step.Preflight(context.Background(), it); err != nil {
+		t.Errorf("Preflight with unknown size: %v", err)
+	}
+}
+
+func TestDiskCheckSharedFilesystem(t *testing.T) {
+	free := func(path string) (uint64, error) { return 10_000, nil }
+	separate := diskCheck{tempDir: "/tmp", musicDir: "/music", reserve: 1000, free: free,
+		device: func(path string) (uint64, error) { return uint64(len(path)), nil }}
+	// 2×3000 on /tmp and 4000 on /music each fit beside the reserve.
+	if err := separate.require(6000, 4000); err != nil {
+		t.Fatalf("separate filesystems: %v", err)
+	}
+
+	shared := separate
+	shared.device = func(path string) (uint64, error) { return 1, nil }
+	err := shared.require(6000, 4000)
+	var diskErr *DiskSpaceError
+	if !errors.As(err, &diskErr) || diskErr.Path != "/tmp" || diskErr.Need != 11_000 {
+		t.Fatalf("shared filesystem error = %v, want /tmp needing 11000", err)
+	}
+	// The reserve counts once for the shared filesystem.
+	if err := shared.require(5000, 4000); err != nil {
+		t.Errorf("9000 plus the reserve should fit in 10000: %v", err)
+	}
+
+	// Paths whose filesystem is unknown are checked on their own.
+	unknown := separate
+	unknown.device = func(path string) (uint64, error) { return 0, errors.New("no stat") }
+	if err := unknown.require(6000, 4000); err != nil {
+		t.Errorf("unknown filesystems: %v", err)
+	}
+}
+
+func TestDownloadAndExtractSteps(t *testing.T) {
+	it := newStepItem(t)
+	if err := os.MkdirAll(filepath.Dir(it.Archive), 0755); err != nil {
+		t.Fatal(err)
+	}
+	it.Item.SourceURL = "http://dl/alb1"
+	if err := (downloadStep{downloader: &fileDownloader{body: "zip"}}).Run(context.Background(), it); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if body, _ := os.ReadFile(it.Archive); string(body) != "zip" {
+		t.Errorf("archive = %q", body)
+	}
+
+	writeZip(t, it.Archive, map[string]string{"CD1/01 Intro.flac": "a", "cover.jpg": "c"})
+	if err := (extractStep{}).Run(context.Background(), it); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(it.Staging, "CD1", "01 Intro.flac")); err != nil {
+		t.Errorf("extracted file missing: %v", err)
+	}
+}
+
+func TestPlaceStep(t *testing.T) {
+	it := newStepItem(t)
+	writeZip(t, it.Archive, map[string]string{"disc/01 Intro.flac": "a", "notes.txt": "n", "cover.jpg": "c"})
+	if _, err := extractZip(context.Background(), it.Archive, it.Staging); err != nil {
+		t.Fatal(err)
+	}
+	if err := (placeStep{}).Run(context.Background(), it); err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	if got := listDir(t, it.Target); strings.Join(got, ",") != "01 Intro.flac,cover.jpg" {
+		t.Errorf("placed files = %v", got)
+	}
+	if it.Skipped || it.Summary != "Placed 2 files" {
+		t.Errorf("skipped = %v, summary = %q", it.Skipped, it.Summary)
+	}
+
+	// The folder exists now, so a second run leaves it alone.
+	again := &StepItem{Job: it.Job, Item: it.Item, Archive: it.Archive, Staging: it.Staging, Target: it.Target}
+	if err := (placeStep{}).Run(context.Background(), again); err != nil {
+		t.Fatalf("place again: %v", err)
+	}
+	if !again.Skipped {
+		t.Error("existing album folder was not skipped")
+	}
+}
+
+func TestPlaceStepKeepsEveryDisc(t *testing.T) {
+	it := newStepItem(t)
+	writeZip(t, it.Archive, map[string]string{
+		"CD1/01 Intro.flac": "a", "CD1/02 Outro.flac": "b", "CD1/cover.jpg": "c",
+		"CD2/01 Intro.flac": "d", "CD2/cover.jpg": "e",
+	})
+	if _, err := extractZip(context.Background(), it.Archive, it.Staging); err != nil {
+		t.Fatal(err)
+	}
+	if err := (placeStep{}).Run(context.Background(), it); err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	want := "02 Outro.flac,CD1 - 01 Intro.flac,CD2 - 01 Intro.flac,cover.jpg"
+	if got := listDir(t, it.Target); strings.Join(got, ",") != want {
+		t.Errorf("placed files = %v, want %s", got, want)
+	}
+	if body, _ := os.ReadFile(filepath.Join(it.Target, "CD2 - 01 Intro.flac")); string(body) != "d" {
+		t.Errorf("second disc track = %q", body)
+	}
+}
+
+func TestPlaceStepWritesPlaceholderWithoutStaging(t *testing.T) {
+	it := newStepItem(t)
+	if err := (placeStep{}).Run(context.Background(), it); err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	body, err := os.ReadFile(filepath.Join(it.Target, "IMPORT_README.txt"))
+	if err != nil || !strings.Contains(string(body), "Album: Cities in Motion") {
+		t.Errorf("placeholder = %q, %v", body, err)
+	}
+}
+
+func TestPlaceStepPlan(t *testing.T) {
+	it := newStepItem(t)
+	if err := (placeStep{}).Plan(context.Background(), it); err != nil {
+		t.Fatal(err)
+	}
+	if it.Item.TargetPath != it.Target || it.Item.Conflict != "" {
+		t.Errorf("plan = %q, %q", it.Item.TargetPath, it.Item.Conflict)
+	}
+	if err := os.MkdirAll(it.Target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := (placeStep{}).Plan(context.Background(), it); err != nil {
+		t.Fatal(err)
+	}
+	if it.Item.Conflict != ConflictExists {
+		t.Errorf("conflict = %q, want %q", it.Item.Conflict, ConflictExists)
+	}
+	if _, err := os.Stat(filepath.Dir(it.Archive)); !os.IsNotExist(err) {
+		t.Error("planning should not touch the temp dir")
+	}
+}
+
+func TestCoverArtStep(t *testing.T) {
+	it := newStepItem(t)
+	it.Item.CoverURL = "http://img/front.PNG?size=large"
+	if err := os.MkdirAll(it.Target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	step := coverArtStep{downloader: &fileDownloader{body: "png"}}
+	if err := step.Run(context.Background(), it); err != nil {
+		t.Fatalf("cover art: %v", err)
+	}
+	if got := listDir(t, it.Target); strings.Join(got, ",") != "cover.png" {
+		t.Errorf("files = %v", got)
+	}
+
+	// Existing artwork is kept.
+	step.downloader = &fileDownloader{body: "other"}
+	it.Item.CoverURL = "http://img/front.jpg"
+	if err := step.Run(context.Background(), it); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDir(t, it.Target); len(got) != 1 {
+		t.Errorf("files = %v, want only the first cover", got)
+	}
+}
+
+func TestHookStep(t *testing.T) {
+	it := newStepItem(t)
+	out := filepath.Join(t.TempDir(), "env")
+	step := hookStep{command: `printf '%s|%s|%s|%s' "$NH_JOB_ID" "$NH_ARTIST" "$NH_ALBUM" "$NH_ALBUM_PATH" > ` + out}
+	if err := step.Run(context.Background(), it); err != nil {
+		t.Fatalf("hook: %v", err)
+	}
+	body, _ := os.ReadFile(out)
+	if want := "job1|Pulse Runner|Cities in Motion|" + it.Target; string(body) != want {
+		t.Errorf("hook env = %q, want %q", body, want)
+	}
+
+	err := hookStep{command: "echo first; echo 'last words' >&2; exit 3"}.Run(context.Background(), it)
+	if err == nil || !strings.HasSuffix(err.Error(), ": last words") {
+		t.Errorf("failing hook error = %v", err)
+	}
+
+	it.Skipped = true
+	if err := (hookStep{command: "exit 1"}).Run(context.Background(), it); err != nil {
+		t.Errorf("hook ran for a skipped album: %v", err)
+	}
+}
+
+func TestCleanupStep(t *testing.T) {
+	it := newStepItem(t)
+	writeZip(t, it.Archive, map[string]string{"a.flac": "a"})
+	if err := os.MkdirAll(it.Staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := (cleanupStep{}).Run(context.Background(), it); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDir(t, filepath.Dir(it.Archive)); len(got) != 0 {
+		t.Errorf("left behind %v", got)
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	phases := func(steps []Step) string {
+		var out []string
+		for _, s := range steps {
+			out = append(out, s.Info().Phase)
+		}
+		return strings.Join(out, ",")
+	}
+	cfg := config.Config{}
+	if got := phases(NewPipeline(cfg, source.StubResolver{}, source.StubDownloader{}, nil)); got != "fetching_source,downloading,extracting,placing,cleanup" {
+		t.Errorf("default pipeline = %s", got)
+	}
+	cfg.FetchCoverArt = true
+	cfg.RefreshLibraryAfterImport = true // without an indexer the step is left out
+	cfg.PostImportHook = "true"
+	if got := phases(NewPipeline(cfg, source.StubResolver{}, source.StubDownloader{}, nil)); got != "fetching_source,downloading,extracting,placing,cover_art,post_import_hook,cleanup" {
+		t.Errorf("optional pipeline = %s", got)
+	}
+}
+
+func TestStepStarts(t *testing.T) {
+	steps := []Step{
+		resolveStep{}, downloadStep{}, extractStep{}, placeStep{}, cleanupStep{},
+	}
+	got := stepStarts(steps)
+	want := []float64{0, 1.0 / 9.5, 5.0 / 9.5, 7.0 / 9.5, 9.0 / 9.5}
+	for idx := range want {
+		if diff := got[idx] - want[idx]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("starts = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestRunPhaseTimeout(t *testing.T) {
+	err := runPhase(context.Background(), PhaseDownloading, 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Phase != PhaseDownloading || errorCode(err) != ErrorCodeTimeout {
+		t.Errorf("runPhase error = %v, want a downloading timeout", err)
+	}
+}
+
+func TestSkippedSurvivesResume(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	job := insertJob(t, r, StatusQueued, false)
+	if err := r.store.UpdateJobItemSkipped(job.ID, "alb1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.store.UpdateJobItemCheckpoint(job.ID, "alb1", PhasePlacing, ""); err != nil {
+		t.Fatal(err)
+	}
+	job, err := r.store.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &job.Items[0]
+	if it := r.stepItem(job, item); !it.Skipped {
+		t.Error("item resumed after placing lost its skipped flag")
+	}
+	// Rewound before placing, the flag is decided again.
+	item.Checkpoint = PhaseExtracting
+	if it := r.stepItem(job, item); it.Skipped {
+		t.Error("item that has not placed yet was marked skipped")
+	}
+}
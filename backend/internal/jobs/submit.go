@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"fmt"
+
+	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/util"
+)
+
+// Duplicate names an import item left out because an active job already covers it.
+type Duplicate struct {
+	SourceID string `json:"sourceId"`
+	JobID    string `json:"jobId"`
+}
+
+// DuplicateError is returned when requeueing a job would import albums that
+// another active job is already importing.
+type DuplicateError struct {
+	Duplicates []Duplicate
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("job %s is already importing %d of these albums", e.Duplicates[0].JobID, len(e.Duplicates))
+}
+
+// Submit queues job after leaving out items that a queued, paused or running
+// job already covers, by source ID or by normalized artist and album, so two
+// jobs never race on one album folder. When every item is covered nothing is
+// inserted and job.Items ends up empty. ErrQueueFull is returned when a new
+// job would exceed QUEUE_MAX_DEPTH.
+func (r *Runner) Submit(job *store.Job) ([]Duplicate, error) {
+	r.submitMu.Lock()
+	defer r.submitMu.Unlock()
+
+	dups, err := r.dropCovered(job)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.Items) == 0 {
+		return dups, nil
+	}
+	if err := r.Admit(); err != nil {
+		return nil, err
+	}
+	if err := r.store.InsertJob(job); err != nil {
+		return nil, err
+	}
+	r.Notify()
+	return dups, nil
+}
+
+// dropCovered removes the items of job that an active job already covers and
+// returns them as duplicates. The job is renamed after its new first item.
+// Callers hold submitMu.
+func (r *Runner) dropCovered(job *store.Job) ([]Duplicate, error) {
+	covered, err := r.activeItems(job)
+	if err != nil {
+		return nil, err
+	}
+	var dups []Duplicate
+	items := job.Items[:0]
+	for idx := range job.Items {
+		if jobID, ok := covered[idx]; ok {
+			dups = append(dups, Duplicate{SourceID: job.Items[idx].SourceID, JobID: jobID})
+			continue
+		}
+		items = append(items, job.Items[idx])
+	}
+	job.Items = items
+	if len(dups) > 0 && len(items) > 0 {
+		// The job is named after its first item, which may have been left out.
+		job.Artist, job.Album = items[0].Artist, items[0].Title
+	}
+	return dups, nil
+}
+
+// checkRequeue returns a *DuplicateError when an active job covers one of the
+// unfinished items of job, which is about to be queued again. Callers hold
+// submitMu.
+func (r *Runner) checkRequeue(job *store.Job) error {
+	covered, err := r.activeItems(job)
+	if err != nil {
+		return err
+	}
+	var dups []Duplicate
+	for idx := range job.Items {
+		if jobID, ok := covered[idx]; ok && job.Items[idx].Status != StatusCompleted {
+			dups = append(dups, Duplicate{SourceID: job.Items[idx].SourceID, JobID: jobID})
+		}
+	}
+	if len(dups) > 0 {
+		return &DuplicateError{Duplicates: dups}
+	}
+	return nil
+}
+
+// activeItems maps the index of each item of job that an active job already
+// covers to that job's ID.
+func (r *Runner) activeItems(job *store.Job) (map[int]string, error) {
+	active, err := r.store.ListActiveJobItems()
+	if err != nil {
+		return nil, err
+	}
+	bySource := map[string]string{}
+	byName := map[string]string{}
+	for idx := range active {
+		it := &active[idx]
+		if it.JobID == job.ID {
+			continue
+		}
+		if _, ok := bySource[it.SourceID]; !ok {
+			bySource[it.SourceID] = it.JobID
+		}
+		if key := nameKey(&store.Job{}, it); key != "" {
+			if _, ok := byName[key]; !ok {
+				byName[key] = it.JobID
+			}
+		}
+	}
+	covered := map[int]string{}
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		if jobID, ok := bySource[item.SourceID]; ok {
+			covered[idx] = jobID
+		} else if jobID, ok := byName[nameKey(job, item)]; ok {
+			covered[idx] = jobID
+		}
+	}
+	return covered, nil
+}
+
+// nameKey is the normalized artist and album of item; empty when the album is unknown.
+func nameKey(job *store.Job, item *store.JobItem) string {
+	req := sourceRequest(job, item)
+	album := util.NormalizeName(req.Album)
+	if album == "" {
+		return ""
+	}
+	return util.NormalizeName(req.Artist) + "\x00" + album
+}
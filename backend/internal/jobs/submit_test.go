@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/store"
+)
+
+// newImport returns a queued job importing one album per source ID.
+func newImport(sourceIDs ...string) *store.Job {
+	job := &store.Job{ID: uuid.NewString(), Status: StatusQueued, Phase: PhaseQueued, MaxAttempts: 1}
+	for _, id := range sourceIDs {
+		job.Items = append(job.Items, store.JobItem{SourceID: id, SourceType: "album", Title: id, Artist: "A", Status: StatusQueued})
+	}
+	job.Artist, job.Album = "A", sourceIDs[0]
+	return job
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	// Each case fills the queue with one job in status, then submits another.
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{status: StatusQueued, wantErr: ErrQueueFull},
+		{status: StatusScheduled, wantErr: ErrQueueFull},
+		{status: StatusWaitingWindow, wantErr: ErrQueueFull},
+		{status: StatusWaitingDisk, wantErr: ErrQueueFull},
+		{status: StatusRunning},
+		{status: StatusPaused},
+		{status: StatusFailed},
+	}
+	for _, tt := range tests {
+		r := newTestRunner(t, config.Config{QueueMaxDepth: 1})
+		insertJob(t, r, tt.status, false)
+		job := newImport("other")
+		if _, err := r.Submit(job); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Submit with a %s job = %v, want %v", tt.status, err, tt.wantErr)
+			continue
+		}
+		status, err := r.store.GetJobStatus(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inserted := status != ""; inserted != (tt.wantErr == nil) {
+			t.Errorf("Submit with a %s job: inserted = %v", tt.status, inserted)
+		}
+	}
+}
+
+func TestSubmitLeavesOutCoveredAlbums(t *testing.T) {
+	tests := []struct {
+		name      string
+		submit    *store.Job
+		wantDups  []string
+		wantItems []string
+	}{
+		{name: "no overlap", submit: newImport("c", "d"), wantItems: []string{"c", "d"}},
+		{name: "partial overlap", submit: newImport("a", "c"), wantDups: []string{"a"}, wantItems: []string{"c"}},
+		{name: "full overlap", submit: newImport("b", "a"), wantDups: []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		r := newTestRunner(t, config.Config{})
+		existing := newImport("a", "b")
+		if _, err := r.Submit(existing); err != nil {
+			t.Fatal(err)
+		}
+		dups, err := r.Submit(tt.submit)
+		if err != nil {
+			t.Fatalf("%s: Submit = %v", tt.name, err)
+		}
+		if len(dups) != len(tt.wantDups) {
+			t.Errorf("%s: duplicates = %+v, want %v", tt.name, dups, tt.wantDups)
+			continue
+		}
+		for idx, dup := range dups {
+			if dup.SourceID != tt.wantDups[idx] || dup.JobID != existing.ID {
+				t.Errorf("%s: duplicate %d = %+v, want %s covered by %s", tt.name, idx, dup, tt.wantDups[idx], existing.ID)
+			}
+		}
+
+		stored, err := r.store.GetJob(tt.submit.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tt.wantItems) == 0 {
+			if stored != nil || len(tt.submit.Items) != 0 {
+				t.Errorf("%s: a fully covered import was inserted", tt.name)
+			}
+			continue
+		}
+		if stored == nil || len(stored.Items) != len(tt.wantItems) {
+			t.Errorf("%s: stored job = %+v, want items %v", tt.name, stored, tt.wantItems)
+			continue
+		}
+		for idx, item := range stored.Items {
+			if item.SourceID != tt.wantItems[idx] {
+				t.Errorf("%s: item %d = %s, want %s", tt.name, idx, item.SourceID, tt.wantItems[idx])
+			}
+		}
+		// The job is named after its first remaining album.
+		if stored.Album != tt.wantItems[0] {
+			t.Errorf("%s: job album = %q, want %q", tt.name, stored.Album, tt.wantItems[0])
+		}
+	}
+}
+
+func TestSubmitMatchesAlbumsByName(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	existing := newImport("a")
+	if _, err := r.Submit(existing); err != nil {
+		t.Fatal(err)
+	}
+	// Another storefront's release of the same album has a different source ID.
+	job := newImport("other-store-a")
+	job.Items[0].Title = " A "
+	dups, err := r.Submit(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dups) != 1 || dups[0].JobID != existing.ID || len(job.Items) != 0 {
+		t.Errorf("duplicates = %+v, items left %d; want the album covered by %s", dups, len(job.Items), existing.ID)
+	}
+}
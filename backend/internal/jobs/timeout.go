@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorCodeTimeout is the job error code for runs that hit a phase or job deadline.
+const ErrorCodeTimeout = "timeout"
+
+// errJobTimeout is the cause of a job context that ran past JOB_TIMEOUT.
+var errJobTimeout = errors.New("job timeout")
+
+// TimeoutError reports a phase, or a whole job run, exceeding its deadline.
+type TimeoutError struct {
+	Phase string
+	Limit time.Duration
+	// Job is set when JOB_TIMEOUT rather than the phase limit expired.
+	Job bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Job {
+		return fmt.Sprintf("job timed out after %s during %s", e.Limit, e.Phase)
+	}
+	return fmt.Sprintf("%s timed out after %s", e.Phase, e.Limit)
+}
+
+// errorCode classifies err for the job's errorCode field.
+func errorCode(err error) string {
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return ErrorCodeTimeout
+	}
+	var recorded *recordedFailure
+	if errors.As(err, &recorded) {
+		return recorded.code
+	}
+	return ""
+}
+
+// runPhase calls fn under a context bounded by limit (zero means no limit).
+// When that bound, and not the parent context, ends fn, the error is a
+// *TimeoutError naming phase.
+func runPhase(ctx context.Context, phase string, limit time.Duration, fn func(context.Context) error) error {
+	if limit <= 0 {
+		return fn(ctx)
+	}
+	phaseCtx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+	err := fn(phaseCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Phase: phase, Limit: limit}
+	}
+	return err
+}
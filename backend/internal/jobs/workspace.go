@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"navidrome-helper/internal/store"
+)
+
+// workspace is the job's own dir under TEMP_DIR holding its archives and
+// staging dirs.
+func (r *Runner) workspace(job *store.Job) string {
+	return filepath.Join(r.cfg.TempDir, job.ID)
+}
+
+func (r *Runner) removeWorkspace(job *store.Job) {
+	if err := os.RemoveAll(r.workspace(job)); err != nil {
+		log.Printf("job %s: remove workspace: %v", job.ID, err)
+	}
+}
+
+// retainWorkspace keeps a failed job's workspace for FAILED_WORKSPACE_RETENTION
+// so it can be inspected; the sweeper removes it afterwards. A zero retention
+// removes it at once, so a manual Retry starts every item from scratch.
+func (r *Runner) retainWorkspace(job *store.Job) {
+	if r.cfg.FailedWorkspaceRetention <= 0 {
+		r.removeWorkspace(job)
+		return
+	}
+	if _, err := os.Stat(r.workspace(job)); err != nil {
+		return
+	}
+	until := time.Now().Add(r.cfg.FailedWorkspaceRetention)
+	_ = r.store.AddJobLog(job.ID, fmt.Sprintf("Temp files kept in %s until %s", r.workspace(job), until.Local().Format(time.RFC3339)))
+}
+
+// sweptPrefix marks a workspace the sweeper has claimed for removal.
+const sweptPrefix = ".swept-"
+
+// sweepWorkspaces removes entries under TEMP_DIR that no live job owns:
+// workspaces of unknown, completed or cancelled jobs, and of failed jobs
+// once FAILED_WORKSPACE_RETENTION has passed. Entries not named after a job
+// id are left alone, except those an interrupted sweep had claimed.
+func (r *Runner) sweepWorkspaces(now time.Time) {
+	entries, err := os.ReadDir(r.cfg.TempDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("sweep workspaces: %v", err)
+		}
+		return
+	}
+	removed := 0
+	for _, e := range entries {
+		id := e.Name()
+		if strings.HasPrefix(id, sweptPrefix) {
+			if err := os.RemoveAll(filepath.Join(r.cfg.TempDir, id)); err != nil {
+				log.Printf("sweep workspaces: %v", err)
+			}
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			continue
+		}
+		if r.sweepEntry(id, filepath.Join(r.cfg.TempDir, id), now) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("swept %d orphaned temp workspaces", removed)
+	}
+}
+
+// sweepEntry removes path unless job id still owns it. The check and a
+// rename out of the job's way happen under r.mu, so a job cannot start
+// running in between; the slow removal runs after r.mu is released.
+func (r *Runner) sweepEntry(id, path string, now time.Time) bool {
+	swept := filepath.Join(filepath.Dir(path), sweptPrefix+id)
+	if !r.claimEntry(id, path, swept, now) {
+		return false
+	}
+	if err := os.RemoveAll(swept); err != nil {
+		log.Printf("sweep workspaces: %v", err)
+	}
+	return true
+}
+
+// claimEntry renames path to swept unless job id still owns it.
+func (r *Runner) claimEntry(id, path, swept string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[id]; ok {
+		return false
+	}
+	job, err := r.store.GetJob(id)
+	if err != nil {
+		log.Printf("sweep workspaces: %v", err)
+		return false
+	}
+	if job != nil {
+		switch job.Status {
+		case StatusCompleted, StatusCancelled:
+		case StatusFailed, StatusPartial, StatusInterrupted:
+			if job.FinishedAt != nil && now.Sub(*job.FinishedAt) < r.cfg.FailedWorkspaceRetention {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if err := os.Rename(path, swept); err != nil {
+		log.Printf("sweep workspaces: %v", err)
+		return false
+	}
+	return true
+}
+
+// sweepLoop sweeps every WORKSPACE_SWEEP_INTERVAL until ctx is done.
+func (r *Runner) sweepLoop(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.cfg.WorkspaceSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.sweepWorkspaces(now)
+		}
+	}
+}
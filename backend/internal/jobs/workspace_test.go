@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/store"
+)
+
+// newTestRunner returns a runner backed by a fresh database, with TEMP_DIR
+// and NAVIDROME_MUSIC_PATH under t.TempDir(). Workers are not started.
+func newTestRunner(t *testing.T, cfg config.Config) *Runner {
+	t.Helper()
+	dir := t.TempDir()
+	st, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TempDir = filepath.Join(dir, "tmp")
+	cfg.NavidromePath = filepath.Join(dir, "music")
+	if err := os.MkdirAll(cfg.TempDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return NewRunner(st, cfg, nil)
+}
+
+// insertJob stores a job in status, finishing it when finished is set, and
+// creates its workspace.
+func insertJob(t *testing.T, r *Runner, status string, finished bool) *store.Job {
+	t.Helper()
+	job := &store.Job{ID: uuid.NewString(), Status: status, Phase: status, MaxAttempts: 1,
+		Items: []store.JobItem{{SourceID: "alb1", SourceType: "album", Status: status}}}
+	if err := r.store.InsertJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if finished {
+		if err := r.store.UpdateJobState(job.ID, status, status, status, 1, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(r.workspace(job), "alb1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestSweepWorkspaces(t *testing.T) {
+	r := newTestRunner(t, config.Config{FailedWorkspaceRetention: time.Hour})
+	completed := insertJob(t, r, StatusCompleted, true)
+	cancelled := insertJob(t, r, StatusCancelled, true)
+	queued := insertJob(t, r, StatusQueued, false)
+	failed := insertJob(t, r, StatusFailed, true)
+	partial := insertJob(t, r, StatusPartial, true)
+	running := insertJob(t, r, StatusRunning, false)
+	r.active[running.ID] = func(error) {}
+	orphan := filepath.Join(r.cfg.TempDir, uuid.NewString())
+	foreign := filepath.Join(r.cfg.TempDir, "not-a-job")
+	// Left behind by a sweep interrupted between claiming and removing.
+	leftover := filepath.Join(r.cfg.TempDir, sweptPrefix+uuid.NewString())
+	for _, dir := range []string{orphan, foreign, leftover} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r.sweepWorkspaces(time.Now())
+	for _, job := range []*store.Job{completed, cancelled} {
+		if exists(r.workspace(job)) {
+			t.Errorf("%s job's workspace was kept", job.Status)
+		}
+	}
+	if exists(orphan) {
+		t.Error("workspace of an unknown job was kept")
+	}
+	for _, job := range []*store.Job{queued, running, failed, partial} {
+		if !exists(r.workspace(job)) {
+			t.Errorf("%s job's workspace was removed", job.Status)
+		}
+	}
+	if !exists(foreign) {
+		t.Error("entry not named after a job was removed")
+	}
+	if exists(leftover) {
+		t.Error("entry claimed by an earlier sweep was kept")
+	}
+	if got := listDir(t, r.cfg.TempDir); len(got) != 5 {
+		t.Errorf("TEMP_DIR after sweeping = %v, want four workspaces and not-a-job", got)
+	}
+
+	// Once the retention has passed, failed jobs lose their workspace too;
+	// live jobs still keep theirs.
+	r.sweepWorkspaces(time.Now().Add(2 * time.Hour))
+	for _, job := range []*store.Job{failed, partial} {
+		if exists(r.workspace(job)) {
+			t.Errorf("%s job's workspace outlived the retention", job.Status)
+		}
+	}
+	for _, job := range []*store.Job{queued, running} {
+		if !exists(r.workspace(job)) {
+			t.Errorf("%s job's workspace was removed after the retention", job.Status)
+		}
+	}
+}
+
+func TestRetainWorkspace(t *testing.T) {
+	r := newTestRunner(t, config.Config{FailedWorkspaceRetention: time.Hour})
+	job := insertJob(t, r, StatusFailed, true)
+	r.retainWorkspace(job)
+	if !exists(r.workspace(job)) {
+		t.Error("failed job's workspace was not retained")
+	}
+
+	// Without a retention the workspace goes at once.
+	r.cfg.FailedWorkspaceRetention = 0
+	r.retainWorkspace(job)
+	if exists(r.workspace(job)) {
+		t.Error("workspace kept with a zero retention")
+	}
+}
+
+func TestSweepWorkspacesWithoutTempDir(t *testing.T) {
+	r := newTestRunner(t, config.Config{})
+	r.cfg.TempDir = filepath.Join(t.TempDir(), "missing")
+	var out strings.Builder
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	r.sweepWorkspaces(time.Now())
+	if out.Len() != 0 {
+		t.Errorf("sweeping a missing TEMP_DIR logged %q", out.String())
+	}
+}
@@ -81,10 +81,17 @@ audioLoop:
 	return entries, nil
 }
 
+var audioExts = map[string]struct{}{
+	".mp3": {}, ".flac": {}, ".ogg": {}, ".wav": {}, ".alac": {}, ".aac": {}, ".m4a": {},
+}
+
+// IsAudioFile reports whether name has one of the audio extensions the indexer counts.
+func IsAudioFile(name string) bool {
+	_, ok := audioExts[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
 func countAudioFiles(root string) (int, error) {
-	audioExts := map[string]struct{}{
-		".mp3": {}, ".flac": {}, ".ogg": {}, ".wav": {}, ".alac": {}, ".aac": {}, ".m4a": {},
-	}
 	count := 0
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -96,8 +103,7 @@ func countAudioFiles(root string) (int, error) {
 			}
 			return nil
 		}
-		ext := strings.ToLower(filepath.Ext(d.Name()))
-		if _, ok := audioExts[ext]; ok {
+		if IsAudioFile(d.Name()) {
 			count++
 		}
 		return nil
@@ -0,0 +1,116 @@
+package library
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/util"
+)
+
+const (
+	MatchExact      = "exact"      // same artist and album, all tracks present
+	MatchIncomplete = "incomplete" // same artist and album, fewer local tracks
+	MatchFuzzy      = "fuzzy"      // same artist, album differs only by edition/qualifiers
+	MatchNone       = "none"
+)
+
+// Match describes how a release relates to the indexed library.
+type Match struct {
+	Status      string `json:"status"`
+	Path        string `json:"path,omitempty"`
+	Album       string `json:"album,omitempty"` // local folder name when it differs in a fuzzy match
+	LocalTracks int    `json:"localTracks"`
+}
+
+var bracketed = regexp.MustCompile(`[\(\[\{][^\)\]\}]*[\)\]\}]`)
+
+// editionWords are dropped when comparing album titles fuzzily.
+var editionWords = map[string]struct{}{
+	"deluxe": {}, "edition": {}, "expanded": {}, "remaster": {}, "remastered": {},
+	"anniversary": {}, "version": {}, "bonus": {}, "tracks": {}, "single": {}, "ep": {},
+}
+
+// MatchQuery is one artist/album pair to look up in the library.
+type MatchQuery struct {
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Tracks int    `json:"tracks,omitempty"`
+}
+
+// MatchBatch answers every query with a single library lookup; results follow query order.
+func MatchBatch(st *store.Store, queries []MatchQuery) ([]Match, error) {
+	seen := map[string]struct{}{}
+	var artists []string
+	for _, q := range queries {
+		norm := util.NormalizeName(q.Artist)
+		if _, ok := seen[norm]; ok || norm == "" {
+			continue
+		}
+		seen[norm] = struct{}{}
+		artists = append(artists, norm)
+	}
+	entries, err := st.ListLibraryByArtists(artists)
+	if err != nil {
+		return nil, fmt.Errorf("match library: %w", err)
+	}
+	byArtist := map[string][]store.LibraryEntry{}
+	for _, e := range entries {
+		byArtist[e.ArtistNorm] = append(byArtist[e.ArtistNorm], e)
+	}
+	out := make([]Match, len(queries))
+	for idx, q := range queries {
+		out[idx] = MatchAlbum(byArtist[util.NormalizeName(q.Artist)], q.Artist, q.Album, q.Tracks)
+	}
+	return out, nil
+}
+
+// MatchAlbum finds the best library entry for artist/album among candidates.
+// Candidates are usually every entry for the artist; tracks is the release's
+// track count (0 when unknown).
+func MatchAlbum(candidates []store.LibraryEntry, artist, album string, tracks int) Match {
+	artistNorm := util.NormalizeName(artist)
+	albumNorm := util.NormalizeName(album)
+	if artistNorm == "" || albumNorm == "" {
+		return Match{Status: MatchNone}
+	}
+
+	var fuzzy *store.LibraryEntry
+	albumFuzzy := fuzzyKey(album)
+	for idx := range candidates {
+		e := &candidates[idx]
+		if e.ArtistNorm != artistNorm {
+			continue
+		}
+		if e.AlbumNorm == albumNorm {
+			m := Match{Status: MatchExact, Path: e.Path, LocalTracks: e.TrackCount}
+			if tracks > 0 && e.TrackCount < tracks {
+				m.Status = MatchIncomplete
+			}
+			return m
+		}
+		if fuzzy == nil && albumFuzzy != "" && fuzzyKey(e.Album) == albumFuzzy {
+			fuzzy = e
+		}
+	}
+	if fuzzy != nil {
+		return Match{Status: MatchFuzzy, Path: fuzzy.Path, Album: fuzzy.Album, LocalTracks: fuzzy.TrackCount}
+	}
+	return Match{Status: MatchNone}
+}
+
+// fuzzyKey normalizes a title and strips bracketed qualifiers and edition words,
+// so "Cities in Motion (Deluxe Edition)" and "Cities in Motion" compare equal.
+func fuzzyKey(title string) string {
+	title = bracketed.ReplaceAllString(title, " ")
+	words := strings.Fields(util.NormalizeName(title))
+	kept := words[:0]
+	for _, w := range words {
+		if _, ok := editionWords[w]; ok {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,111 @@
+package library
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/util"
+)
+
+func entry(artist, album string, tracks int) store.LibraryEntry {
+	return store.LibraryEntry{
+		Artist:     artist,
+		Album:      album,
+		Path:       filepath.Join("/music", artist, album),
+		TrackCount: tracks,
+		ArtistNorm: util.NormalizeName(artist),
+		AlbumNorm:  util.NormalizeName(album),
+	}
+}
+
+func TestMatchAlbum(t *testing.T) {
+	library := []store.LibraryEntry{
+		entry("Pulse Runner", "Cities in Motion", 10),
+		entry("Pulse Runner", "Afterglow (Remastered)", 8),
+		entry("Someone Else", "Night Drive", 12),
+	}
+	tests := []struct {
+		name          string
+		artist, album string
+		tracks        int
+		want          Match
+	}{
+		{name: "exact", artist: "Pulse Runner", album: "Cities in Motion", tracks: 10,
+			want: Match{Status: MatchExact, Path: "/music/Pulse Runner/Cities in Motion", LocalTracks: 10}},
+		{name: "exact ignores case and punctuation", artist: "pulse-runner", album: "cities, in motion!",
+			want: Match{Status: MatchExact, Path: "/music/Pulse Runner/Cities in Motion", LocalTracks: 10}},
+		{name: "unknown track count", artist: "Pulse Runner", album: "Cities in Motion", tracks: 0,
+			want: Match{Status: MatchExact, Path: "/music/Pulse Runner/Cities in Motion", LocalTracks: 10}},
+		{name: "incomplete", artist: "Pulse Runner", album: "Cities in Motion", tracks: 12,
+			want: Match{Status: MatchIncomplete, Path: "/music/Pulse Runner/Cities in Motion", LocalTracks: 10}},
+		{name: "fuzzy edition words", artist: "Pulse Runner", album: "Cities in Motion Deluxe Edition", tracks: 14,
+			want: Match{Status: MatchFuzzy, Path: "/music/Pulse Runner/Cities in Motion", Album: "Cities in Motion", LocalTracks: 10}},
+		{name: "fuzzy brackets on the local side", artist: "Pulse Runner", album: "Afterglow",
+			want: Match{Status: MatchFuzzy, Path: "/music/Pulse Runner/Afterglow (Remastered)", Album: "Afterglow (Remastered)", LocalTracks: 8}},
+		{name: "fuzzy square brackets", artist: "Pulse Runner", album: "Afterglow [2024 Expanded]",
+			want: Match{Status: MatchFuzzy, Path: "/music/Pulse Runner/Afterglow (Remastered)", Album: "Afterglow (Remastered)", LocalTracks: 8}},
+		{name: "other album", artist: "Pulse Runner", album: "Night Drive",
+			want: Match{Status: MatchNone}},
+		{name: "other artist", artist: "Someone Else", album: "Cities in Motion",
+			want: Match{Status: MatchNone}},
+		{name: "empty album", artist: "Pulse Runner", album: "",
+			want: Match{Status: MatchNone}},
+		{name: "only edition words", artist: "Pulse Runner", album: "(Deluxe)",
+			want: Match{Status: MatchNone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchAlbum(library, tt.artist, tt.album, tt.tracks); got != tt.want {
+				t.Errorf("MatchAlbum(%q, %q, %d) = %+v, want %+v", tt.artist, tt.album, tt.tracks, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyKey(t *testing.T) {
+	tests := map[string]string{
+		"Cities in Motion":                    "cities in motion",
+		"Cities in Motion (Deluxe Edition)":   "cities in motion",
+		"Cities in Motion [Remastered 2024]":  "cities in motion",
+		"Cities in Motion - Expanded Version": "cities in motion",
+		"Afterglow {Bonus Tracks}":            "afterglow",
+		"Singles Collection":                  "singles collection",
+		"Deluxe":                              "",
+	}
+	for in, want := range tests {
+		if got := fuzzyKey(in); got != want {
+			t.Errorf("fuzzyKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchBatch(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ReplaceLibraryIndex([]store.LibraryEntry{
+		entry("Pulse Runner", "Cities in Motion", 10),
+		entry("Someone Else", "Night Drive (Deluxe)", 12),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := MatchBatch(st, []MatchQuery{
+		{Artist: "Someone Else", Album: "Night Drive"},
+		{Artist: "Pulse Runner", Album: "Cities in Motion", Tracks: 11},
+		{Artist: "Nobody", Album: "Cities in Motion"},
+		{Artist: "PULSE RUNNER", Album: "Cities in Motion", Tracks: 10},
+	})
+	if err != nil {
+		t.Fatalf("MatchBatch: %v", err)
+	}
+	var statuses []string
+	for _, m := range got {
+		statuses = append(statuses, m.Status)
+	}
+	if want := []string{MatchFuzzy, MatchIncomplete, MatchNone, MatchExact}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
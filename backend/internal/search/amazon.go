@@ -0,0 +1,250 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// AmazonProvider queries an Amazon Music compatible HTTP API.
+type AmazonProvider struct {
+	name    string
+	baseURL string
+	client  *http.Client
+}
+
+// NewAmazonProvider returns a provider rooted at baseURL (AMAZON_API_BASE_URL).
+func NewAmazonProvider(baseURL string, client *http.Client) *AmazonProvider {
+	return NewCompatibleProvider("amazon", baseURL, client)
+}
+
+// NewCompatibleProvider returns a provider for another storefront exposing the same API shape.
+func NewCompatibleProvider(name, baseURL string, client *http.Client) *AmazonProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &AmazonProvider{name: name, baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+func (p *AmazonProvider) Name() string {
+	return p.name
+}
+
+// Search calls GET {base}/search and maps the upstream payload to a page.
+// Qualifiers are sent as parameters and the upstream does the filtering, so
+// the page, Total and NextCursor all describe the same result set.
+func (p *AmazonProvider) Search(ctx context.Context, q Query) (Page, error) {
+	params := url.Values{}
+	params.Set("q", q.Text)
+	if q.Type != "" {
+		params.Set("type", q.Type)
+	}
+	if q.Artist != "" {
+		params.Set("artist", q.Artist)
+	}
+	if q.Album != "" {
+		params.Set("album", q.Album)
+	}
+	if q.Year != 0 {
+		params.Set("year", strconv.Itoa(q.Year))
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		params.Set("cursor", q.Cursor)
+	}
+	var payload amazonSearchResponse
+	if err := p.get(ctx, "/search?"+params.Encode(), &payload); err != nil {
+		return Page{}, err
+	}
+	page := Page{Items: make([]Result, 0, len(payload.Results)), Total: payload.Total, NextCursor: payload.NextCursor}
+	for _, item := range payload.Results {
+		page.Items = append(page.Items, item.toResult())
+	}
+	if page.Total < len(page.Items) {
+		page.Total = len(page.Items)
+	}
+	return page, nil
+}
+
+// Album calls GET {base}/albums/{id}.
+func (p *AmazonProvider) Album(ctx context.Context, id string) (*Album, error) {
+	var payload amazonAlbum
+	if err := p.get(ctx, "/albums/"+url.PathEscape(id), &payload); err != nil {
+		return nil, err
+	}
+	album := payload.toAlbum()
+	album.Source = p.name
+	return &album, nil
+}
+
+// ArtistAlbums calls GET {base}/artists/{id}/albums.
+func (p *AmazonProvider) ArtistAlbums(ctx context.Context, artistID string) (*Discography, error) {
+	var payload amazonDiscography
+	if err := p.get(ctx, "/artists/"+url.PathEscape(artistID)+"/albums", &payload); err != nil {
+		return nil, err
+	}
+	disco := &Discography{ArtistID: artistID, Artist: payload.Name, Albums: make([]Result, 0, len(payload.Albums))}
+	for _, item := range payload.Albums {
+		res := item.toResult()
+		if res.ArtistID == "" {
+			res.ArtistID = artistID
+		}
+		disco.Albums = append(disco.Albums, res)
+	}
+	return disco, nil
+}
+
+func (p *AmazonProvider) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", p.name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s request: unexpected status %d", p.name, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", p.name, err)
+	}
+	return nil
+}
+
+type amazonSearchResponse struct {
+	Results    []amazonItem `json:"results"`
+	Total      int          `json:"total"`
+	NextCursor string       `json:"nextCursor"`
+}
+
+type amazonItem struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	Artist          string `json:"artist"`
+	ArtistID        string `json:"artistId"`
+	AlbumID         string `json:"albumId"`
+	AlbumTitle      string `json:"albumTitle"`
+	ImageURL        string `json:"imageUrl"`
+	TrackCount      int    `json:"trackCount"`
+	DurationSeconds int    `json:"durationSeconds"`
+	ReleaseDate     string `json:"releaseDate"`
+}
+
+func (it amazonItem) toResult() Result {
+	typ := strings.ToLower(it.Type)
+	if typ != "song" {
+		typ = "album"
+	}
+	albumID := it.AlbumID
+	albumTitle := it.AlbumTitle
+	if typ == "album" {
+		if albumID == "" {
+			albumID = it.ID
+		}
+		if albumTitle == "" {
+			albumTitle = it.Title
+		}
+	}
+	return Result{
+		ID:         it.ID,
+		Type:       typ,
+		Title:      it.Title,
+		Artist:     it.Artist,
+		ArtistID:   it.ArtistID,
+		AlbumID:    albumID,
+		AlbumTitle: albumTitle,
+		CoverURL:   it.ImageURL,
+		Tracks:     it.TrackCount,
+		Duration:   it.DurationSeconds,
+		Year:       releaseYear(it.ReleaseDate),
+	}
+}
+
+// releaseYear extracts the year from an upstream YYYY[-MM-DD] date.
+func releaseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+type amazonDiscography struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Albums []amazonItem `json:"albums"`
+}
+
+type amazonAlbum struct {
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Artist      string        `json:"artist"`
+	ArtistID    string        `json:"artistId"`
+	ImageURL    string        `json:"imageUrl"`
+	ReleaseDate string        `json:"releaseDate"`
+	Label       string        `json:"label"`
+	Explicit    bool          `json:"explicit"`
+	Tracks      []amazonTrack `json:"tracks"`
+}
+
+type amazonTrack struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	TrackNumber     int    `json:"trackNumber"`
+	DiscNumber      int    `json:"discNumber"`
+	DurationSeconds int    `json:"durationSeconds"`
+	Explicit        bool   `json:"explicit"`
+}
+
+func (a amazonAlbum) toAlbum() Album {
+	album := Album{
+		ID:          a.ID,
+		Title:       a.Title,
+		Artist:      a.Artist,
+		ArtistID:    a.ArtistID,
+		CoverURL:    a.ImageURL,
+		ReleaseDate: a.ReleaseDate,
+		Label:       a.Label,
+		Explicit:    a.Explicit,
+		Tracks:      make([]Track, 0, len(a.Tracks)),
+	}
+	for idx, t := range a.Tracks {
+		disc := t.DiscNumber
+		if disc == 0 {
+			disc = 1
+		}
+		number := t.TrackNumber
+		if number == 0 {
+			number = idx + 1
+		}
+		album.Tracks = append(album.Tracks, Track{
+			ID:       t.ID,
+			Title:    t.Title,
+			Number:   number,
+			Disc:     disc,
+			Duration: t.DurationSeconds,
+			Explicit: t.Explicit,
+		})
+		album.Duration += t.DurationSeconds
+		if t.Explicit {
+			album.Explicit = true
+		}
+	}
+	return album
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newUpstream serves canned JSON bodies by request path and records the
+// query string of the last request.
+func newUpstream(t *testing.T, routes map[string]string) (*httptest.Server, *string) {
+	t.Helper()
+	var lastQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastQuery = r.URL.RawQuery
+		if r.URL.Path == "/albums/broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		body, ok := routes[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &lastQuery
+}
+
+func TestAmazonSearch(t *testing.T) {
+	srv, lastQuery := newUpstream(t, map[string]string{
+		"/search": `{"total": 1, "nextCursor": "c2", "results": [
+			{"id": "alb1", "type": "ALBUM", "title": "Cities in Motion", "artist": "Pulse Runner", "artistId": "art1",
+			 "imageUrl": "http://img/1.jpg", "trackCount": 10, "releaseDate": "2024-03-01"},
+			{"id": "trk1", "type": "song", "title": "Night Drive", "artist": "Pulse Runner", "albumId": "alb1",
+			 "albumTitle": "Cities in Motion", "durationSeconds": 215, "releaseDate": "2024"}
+		]}`,
+	})
+	p := NewAmazonProvider(srv.URL+"/", nil)
+
+	page, err := p.Search(context.Background(), Query{Text: "pulse", Artist: "Pulse Runner", Year: 2024, Limit: 5, Cursor: "c1"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, want := range []string{"q=pulse", "artist=Pulse+Runner", "year=2024", "limit=5", "cursor=c1"} {
+		if !strings.Contains(*lastQuery, want) {
+			t.Errorf("query %q lacks %q", *lastQuery, want)
+		}
+	}
+	if page.NextCursor != "c2" || page.Total != 2 || len(page.Items) != 2 {
+		t.Fatalf("page = %+v", page)
+	}
+	album, song := page.Items[0], page.Items[1]
+	wantAlbum := Result{ID: "alb1", Type: "album", Title: "Cities in Motion", Artist: "Pulse Runner", ArtistID: "art1",
+		AlbumID: "alb1", AlbumTitle: "Cities in Motion", CoverURL: "http://img/1.jpg", Tracks: 10, Year: 2024}
+	if !reflect.DeepEqual(album, wantAlbum) {
+		t.Errorf("album = %+v, want %+v", album, wantAlbum)
+	}
+	if song.Type != "song" || song.AlbumID != "alb1" || song.Duration != 215 || song.Year != 2024 {
+		t.Errorf("song = %+v", song)
+	}
+}
+
+func TestAmazonSearchLeavesFilteringUpstream(t *testing.T) {
+	srv, lastQuery := newUpstream(t, map[string]string{
+		"/search": `{"total": 40, "nextCursor": "c2", "results": [
+			{"id": "a", "type": "album", "title": "Cities in Motion", "artist": "Pulse Runner", "releaseDate": "2024-01-01"},
+			{"id": "b", "type": "album", "title": "Cities in Motion", "artist": "Someone Else", "releaseDate": "2024-01-01"}
+		]}`,
+	})
+	page, err := NewAmazonProvider(srv.URL, nil).Search(context.Background(), Query{Artist: "pulse", Album: "cities", Type: "album", Limit: 2})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, want := range []string{"artist=pulse", "album=cities", "type=album", "limit=2"} {
+		if !strings.Contains(*lastQuery, want) {
+			t.Errorf("query %q lacks %q", *lastQuery, want)
+		}
+	}
+	// The page is kept as the upstream sent it so it stays consistent with
+	// its total and cursor.
+	if len(page.Items) != 2 || page.Total != 40 || page.NextCursor != "c2" {
+		t.Errorf("page = %+v", page)
+	}
+}
+
+func TestAmazonAlbum(t *testing.T) {
+	srv, _ := newUpstream(t, map[string]string{
+		"/albums/alb%201": `{"id": "alb 1", "title": "Cities in Motion", "artist": "Pulse Runner", "artistId": "art1",
+			"releaseDate": "2024-03-01", "label": "Nightshift", "tracks": [
+			{"id": "t1", "title": "Intro", "durationSeconds": 60},
+			{"id": "t2", "title": "Night Drive", "trackNumber": 7, "discNumber": 2, "durationSeconds": 200, "explicit": true}
+		]}`,
+	})
+	album, err := NewAmazonProvider(srv.URL, nil).Album(context.Background(), "alb 1")
+	if err != nil {
+		t.Fatalf("Album: %v", err)
+	}
+	if album.Source != "amazon" || album.Label != "Nightshift" || album.Duration != 260 || !album.Explicit {
+		t.Errorf("album = %+v", album)
+	}
+	if len(album.Tracks) != 2 {
+		t.Fatalf("tracks = %+v", album.Tracks)
+	}
+	if got := album.Tracks[0]; got.Number != 1 || got.Disc != 1 {
+		t.Errorf("first track = %+v, want number 1 disc 1", got)
+	}
+	if got := album.Tracks[1]; got.Number != 7 || got.Disc != 2 || !got.Explicit {
+		t.Errorf("second track = %+v", got)
+	}
+}
+
+func TestAmazonArtistAlbums(t *testing.T) {
+	srv, _ := newUpstream(t, map[string]string{
+		"/artists/art1/albums": `{"id": "art1", "name": "Pulse Runner", "albums": [
+			{"id": "alb1", "type": "album", "title": "Cities in Motion", "artist": "Pulse Runner"},
+			{"id": "alb2", "type": "album", "title": "Afterglow", "artist": "Pulse Runner", "artistId": "other"}
+		]}`,
+	})
+	disco, err := NewAmazonProvider(srv.URL, nil).ArtistAlbums(context.Background(), "art1")
+	if err != nil {
+		t.Fatalf("ArtistAlbums: %v", err)
+	}
+	if disco.Artist != "Pulse Runner" || disco.ArtistID != "art1" || len(disco.Albums) != 2 {
+		t.Fatalf("discography = %+v", disco)
+	}
+	if disco.Albums[0].ArtistID != "art1" || disco.Albums[1].ArtistID != "other" {
+		t.Errorf("artist ids = %q, %q", disco.Albums[0].ArtistID, disco.Albums[1].ArtistID)
+	}
+}
+
+func TestAmazonErrors(t *testing.T) {
+	srv, _ := newUpstream(t, map[string]string{"/search": `{not json`})
+	p := NewCompatibleProvider("tidal", srv.URL, nil)
+	ctx := context.Background()
+
+	if _, err := p.Album(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Album(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := p.ArtistAlbums(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ArtistAlbums(missing) error = %v, want ErrNotFound", err)
+	}
+	_, err := p.Album(ctx, "broken")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "tidal request: unexpected status 500") {
+		t.Errorf("Album(broken) error = %v, want unexpected status 500", err)
+	}
+	if _, err := p.Search(ctx, Query{Text: "x"}); err == nil || !strings.Contains(err.Error(), "decode tidal response") {
+		t.Errorf("Search with bad JSON error = %v", err)
+	}
+}
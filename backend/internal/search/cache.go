@@ -0,0 +1,178 @@
+package search
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"navidrome-helper/internal/util"
+)
+
+const maxCacheEntries = 512
+
+// CacheStats reports how effective the search cache has been.
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Coalesced int64 `json:"coalesced"`
+	Entries   int   `json:"entries"`
+}
+
+// Cache wraps a provider with a TTL cache and coalesces concurrent identical queries.
+type Cache struct {
+	next SearchProvider
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*cacheCall
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+}
+
+type cacheEntry struct {
+	page    Page
+	expires time.Time
+}
+
+type cacheCall struct {
+	done chan struct{}
+	page Page
+	err  error
+	// abandoned is set when the call failed because its caller's context
+	// ended, so the outcome says nothing about the query.
+	abandoned bool
+}
+
+// NewCache caches pages from next for ttl.
+func NewCache(next SearchProvider, ttl time.Duration) *Cache {
+	return &Cache{
+		next:     next,
+		ttl:      ttl,
+		entries:  map[string]cacheEntry{},
+		inflight: map[string]*cacheCall{},
+	}
+}
+
+func (c *Cache) Name() string {
+	return c.next.Name()
+}
+
+// Search serves q from cache when fresh, otherwise joins or starts an upstream
+// call. The call runs under the context of the caller that started it; when
+// that caller goes away, the callers that joined it start over instead of
+// failing with its context error.
+func (c *Cache) Search(ctx context.Context, q Query) (Page, error) {
+	key := cacheKey(q)
+	for {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return copyPage(e.page), nil
+		}
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+		select {
+		case <-call.done:
+			if call.abandoned && ctx.Err() == nil {
+				continue
+			}
+			return copyPage(call.page), call.err
+		case <-ctx.Done():
+			return Page{}, ctx.Err()
+		}
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	call.page, call.err = c.next.Search(ctx, q)
+	call.abandoned = call.err != nil && ctx.Err() != nil
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	// Degraded pages are served once but not cached so the next query retries.
+	if call.err == nil && len(call.page.Errors) == 0 {
+		c.store(key, call.page, time.Now())
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return copyPage(call.page), call.err
+}
+
+// Album is not cached; detail lookups are rare compared to search-as-you-type.
+func (c *Cache) Album(ctx context.Context, id string) (*Album, error) {
+	return c.next.Album(ctx, id)
+}
+
+// ArtistAlbums is not cached either.
+func (c *Cache) ArtistAlbums(ctx context.Context, artistID string) (*Discography, error) {
+	return c.next.ArtistAlbums(ctx, artistID)
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Entries:   entries,
+	}
+}
+
+// store must be called with c.mu held.
+func (c *Cache) store(key string, page Page, now time.Time) {
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= maxCacheEntries {
+		// Still full of live entries; drop an arbitrary one.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = cacheEntry{page: page, expires: now.Add(c.ttl)}
+}
+
+func cacheKey(q Query) string {
+	return strings.Join([]string{
+		util.NormalizeName(q.Text), q.Type, util.NormalizeName(q.Artist), util.NormalizeName(q.Album),
+		strconv.Itoa(q.Year), strconv.Itoa(q.Limit), q.Cursor,
+	}, "\x1f")
+}
+
+// copyPage gives callers their own slices, down to each result's Sources, so
+// cached and shared pages are never mutated.
+func copyPage(p Page) Page {
+	if p.Items != nil {
+		p.Items = append([]Result(nil), p.Items...)
+		for idx := range p.Items {
+			if p.Items[idx].Sources != nil {
+				p.Items[idx].Sources = append([]string(nil), p.Items[idx].Sources...)
+			}
+		}
+	}
+	if p.Errors != nil {
+		p.Errors = append([]ProviderError(nil), p.Errors...)
+	}
+	return p
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubProvider answers searches with search and counts the calls.
+type stubProvider struct {
+	name   string
+	search func(ctx context.Context, q Query) (Page, error)
+	calls  atomic.Int64
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) Search(ctx context.Context, q Query) (Page, error) {
+	p.calls.Add(1)
+	return p.search(ctx, q)
+}
+
+func (p *stubProvider) Album(ctx context.Context, id string) (*Album, error) {
+	return nil, ErrNotFound
+}
+
+func (p *stubProvider) ArtistAlbums(ctx context.Context, artistID string) (*Discography, error) {
+	return nil, ErrNotFound
+}
+
+func pageOf(ids ...string) Page {
+	page := Page{Total: len(ids)}
+	for _, id := range ids {
+		page.Items = append(page.Items, Result{ID: id, Type: "album", Title: id, Artist: "A"})
+	}
+	return page
+}
+
+func TestCacheHitsAndExpiry(t *testing.T) {
+	p := &stubProvider{name: "stub", search: func(ctx context.Context, q Query) (Page, error) {
+		page := pageOf("a")
+		page.Items[0].Sources = []string{"amazon", "qobuz"}
+		return page, nil
+	}}
+	c := NewCache(p, time.Hour)
+	ctx := context.Background()
+
+	first, err := c.Search(ctx, Query{Text: "Cities  in Motion"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	first.Items[0].ID = "mutated"
+	first.Items[0].Sources[0] = "mutated"
+	first.Items[0].Sources = append(first.Items[0].Sources[:1], "extra")
+	second, err := c.Search(ctx, Query{Text: "cities in motion"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if second.Items[0].ID != "a" {
+		t.Errorf("cached page was mutated through a returned copy")
+	}
+	if got := second.Items[0].Sources; len(got) != 2 || got[0] != "amazon" || got[1] != "qobuz" {
+		t.Errorf("cached sources = %v, mutated through a returned copy", got)
+	}
+	if _, err := c.Search(ctx, Query{Text: "cities in motion", Limit: 5}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := p.calls.Load(); got != 2 {
+		t.Errorf("upstream calls = %d, want 2", got)
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 2 || st.Entries != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	expired := NewCache(p, 0)
+	_, _ = expired.Search(ctx, Query{Text: "x"})
+	_, _ = expired.Search(ctx, Query{Text: "x"})
+	if got := p.calls.Load(); got != 4 {
+		t.Errorf("upstream calls without a TTL = %d, want 4", got)
+	}
+}
+
+func TestCacheSkipsDegradedAndFailedPages(t *testing.T) {
+	fail := true
+	p := &stubProvider{name: "stub", search: func(ctx context.Context, q Query) (Page, error) {
+		if fail {
+			return Page{}, errors.New("upstream down")
+		}
+		page := pageOf("a")
+		page.Errors = []ProviderError{{Provider: "other", Error: "timed out"}}
+		return page, nil
+	}}
+	c := NewCache(p, time.Hour)
+	ctx := context.Background()
+	if _, err := c.Search(ctx, Query{Text: "x"}); err == nil {
+		t.Fatal("expected the upstream error")
+	}
+	fail = false
+	_, _ = c.Search(ctx, Query{Text: "x"})
+	_, _ = c.Search(ctx, Query{Text: "x"})
+	if got := p.calls.Load(); got != 3 {
+		t.Errorf("upstream calls = %d, want 3", got)
+	}
+}
+
+func TestCacheCoalescesConcurrentQueries(t *testing.T) {
+	release := make(chan struct{})
+	p := &stubProvider{name: "stub", search: func(ctx context.Context, q Query) (Page, error) {
+		<-release
+		return pageOf("a"), nil
+	}}
+	c := NewCache(p, time.Hour)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Search(context.Background(), Query{Text: "x"})
+			errs <- err
+		}()
+	}
+	waitFor(t, func() bool { return c.Stats().Coalesced == callers-1 })
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Search: %v", err)
+		}
+	}
+	if got := p.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+}
+
+func TestCacheWaiterOutlivesCancelledLeader(t *testing.T) {
+	var n atomic.Int64
+	p := &stubProvider{name: "stub", search: func(ctx context.Context, q Query) (Page, error) {
+		if n.Add(1) == 1 {
+			<-ctx.Done()
+			return Page{}, ctx.Err()
+		}
+		return pageOf("a"), nil
+	}}
+	c := NewCache(p, time.Hour)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.Search(leaderCtx, Query{Text: "x"})
+		leaderErr <- err
+	}()
+	waitFor(t, func() bool { return c.Stats().Misses == 1 })
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		page, err := c.Search(context.Background(), Query{Text: "x"})
+		if err == nil && len(page.Items) != 1 {
+			err = errors.New("empty page")
+		}
+		waiterErr <- err
+	}()
+	waitFor(t, func() bool { return c.Stats().Coalesced == 1 })
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	if err := <-waiterErr; err != nil {
+		t.Errorf("waiter error = %v, want a fresh result", err)
+	}
+	if got := p.calls.Load(); got != 2 {
+		t.Errorf("upstream calls = %d, want 2", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,216 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"navidrome-helper/internal/util"
+)
+
+// ProviderError records a provider that failed or timed out while building a page.
+type ProviderError struct {
+	Provider string `json:"provider"`
+	Error    string `json:"error"`
+}
+
+// Federated fans a query out to several providers and merges their results.
+// Providers are listed in priority order: earlier providers rank higher.
+type Federated struct {
+	providers []SearchProvider
+	timeout   time.Duration
+}
+
+// NewFederated queries providers in parallel, giving each at most timeout to answer.
+func NewFederated(providers []SearchProvider, timeout time.Duration) *Federated {
+	return &Federated{providers: providers, timeout: timeout}
+}
+
+func (f *Federated) Name() string {
+	names := make([]string, 0, len(f.providers))
+	for _, p := range f.providers {
+		names = append(names, p.Name())
+	}
+	return "federated(" + strings.Join(names, ",") + ")"
+}
+
+type providerPage struct {
+	page Page
+	err  error
+}
+
+// Search queries every provider still holding results for q and merges the pages.
+// The merged page may hold up to q.Limit items per provider; the cursor tracks
+// each provider separately. A provider that fails is reported in Errors and
+// left out of later pages, so a broken upstream cannot keep pagination going.
+// Total adds up the provider totals less the duplicates merged on this page;
+// providers overlap beyond what one page shows, so it is then marked approximate.
+func (f *Federated) Search(ctx context.Context, q Query) (Page, error) {
+	cursors, err := decodeFederatedCursor(q.Cursor, f.providers)
+	if err != nil {
+		return Page{}, err
+	}
+
+	pages := make([]providerPage, len(f.providers))
+	var wg sync.WaitGroup
+	for idx, p := range f.providers {
+		cursor, ok := cursors[p.Name()]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int, p SearchProvider, cursor string) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, f.timeout)
+			defer cancel()
+			sub := q
+			sub.Cursor = cursor
+			page, err := p.Search(pctx, sub)
+			pages[idx] = providerPage{page: page, err: err}
+		}(idx, p, cursor)
+	}
+	wg.Wait()
+
+	out := Page{Items: []Result{}}
+	next := map[string]string{}
+	merged := map[string]int{}
+	succeeded := 0
+	var firstErr error
+	for idx, p := range f.providers {
+		if _, ok := cursors[p.Name()]; !ok {
+			continue
+		}
+		res := pages[idx]
+		if res.err != nil {
+			if errors.Is(res.err, ErrInvalidCursor) {
+				return Page{}, res.err
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			msg := res.err.Error()
+			if errors.Is(res.err, context.DeadlineExceeded) {
+				msg = "timed out"
+			}
+			out.Errors = append(out.Errors, ProviderError{Provider: p.Name(), Error: msg})
+			continue
+		}
+		succeeded++
+		out.Total += res.page.Total
+		if res.page.NextCursor != "" {
+			next[p.Name()] = res.page.NextCursor
+		}
+		for _, item := range res.page.Items {
+			key := mergeKey(item)
+			if at, ok := merged[key]; ok {
+				out.Items[at].Sources = append(out.Items[at].Sources, p.Name())
+				out.Total--
+				out.TotalApproximate = true
+				continue
+			}
+			item.Sources = []string{p.Name()}
+			item.Rank = idx
+			merged[key] = len(out.Items)
+			out.Items = append(out.Items, item)
+		}
+	}
+	if succeeded == 0 && firstErr != nil {
+		return Page{}, fmt.Errorf("all providers failed: %w", firstErr)
+	}
+
+	// Items were appended provider by provider, so a stable sort on the
+	// provider index keeps each provider's own ordering.
+	sort.SliceStable(out.Items, func(a, b int) bool {
+		return out.Items[a].Rank < out.Items[b].Rank
+	})
+	for idx := range out.Items {
+		out.Items[idx].Rank = idx + 1
+	}
+	if len(next) > 0 {
+		out.NextCursor = encodeFederatedCursor(next)
+	}
+	return out, nil
+}
+
+// Album asks providers in priority order and returns the first match.
+func (f *Federated) Album(ctx context.Context, id string) (*Album, error) {
+	var lastErr error = ErrNotFound
+	for _, p := range f.providers {
+		pctx, cancel := context.WithTimeout(ctx, f.timeout)
+		album, err := p.Album(pctx, id)
+		cancel()
+		if err == nil {
+			return album, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// ArtistAlbums asks providers in priority order and returns the first match.
+func (f *Federated) ArtistAlbums(ctx context.Context, artistID string) (*Discography, error) {
+	var lastErr error = ErrNotFound
+	for _, p := range f.providers {
+		pctx, cancel := context.WithTimeout(ctx, f.timeout)
+		disco, err := p.ArtistAlbums(pctx, artistID)
+		cancel()
+		if err == nil {
+			for idx := range disco.Albums {
+				disco.Albums[idx].Sources = []string{p.Name()}
+			}
+			return disco, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// mergeKey identifies the same release across storefronts.
+func mergeKey(r Result) string {
+	artist := util.NormalizeName(r.Artist)
+	if r.Type == "song" {
+		return "song\x1f" + artist + "\x1f" + util.NormalizeName(r.AlbumTitle) + "\x1f" + util.NormalizeName(r.Title)
+	}
+	album := r.AlbumTitle
+	if album == "" {
+		album = r.Title
+	}
+	return "album\x1f" + artist + "\x1f" + util.NormalizeName(album)
+}
+
+// decodeFederatedCursor maps provider name to its cursor. An empty cursor
+// starts every provider from the beginning; providers missing from a
+// non-empty cursor are exhausted.
+func decodeFederatedCursor(raw string, providers []SearchProvider) (map[string]string, error) {
+	if raw == "" {
+		out := make(map[string]string, len(providers))
+		for _, p := range providers {
+			out[p.Name()] = ""
+		}
+		return out, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var out map[string]string
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return out, nil
+}
+
+func encodeFederatedCursor(cursors map[string]string) string {
+	data, _ := json.Marshal(cursors)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFederatedMergesByPriority(t *testing.T) {
+	first := &stubProvider{name: "amazon", search: func(ctx context.Context, q Query) (Page, error) {
+		return Page{Total: 2, Items: []Result{
+			{ID: "a1", Type: "album", Title: "Cities in Motion", Artist: "Pulse Runner"},
+			{ID: "a2", Type: "album", Title: "Afterglow", Artist: "Pulse Runner"},
+		}}, nil
+	}}
+	second := &stubProvider{name: "tidal", search: func(ctx context.Context, q Query) (Page, error) {
+		return Page{Total: 2, NextCursor: "t2", Items: []Result{
+			{ID: "t9", Type: "album", Title: "Night Drive", Artist: "Pulse Runner"},
+			{ID: "t1", Type: "album", Title: "CITIES IN MOTION", Artist: "pulse runner"},
+		}}, nil
+	}}
+	f := NewFederated([]SearchProvider{first, second}, time.Second)
+
+	page, err := f.Search(context.Background(), Query{Text: "pulse"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	for _, it := range page.Items {
+		ids = append(ids, it.ID)
+	}
+	if want := []string{"a1", "a2", "t9"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if got := page.Items[0].Sources; !reflect.DeepEqual(got, []string{"amazon", "tidal"}) {
+		t.Errorf("merged sources = %v", got)
+	}
+	for idx, it := range page.Items {
+		if it.Rank != idx+1 {
+			t.Errorf("%s rank = %d, want %d", it.ID, it.Rank, idx+1)
+		}
+	}
+	// One release was merged, so the summed total is only an estimate.
+	if page.Total != 3 || !page.TotalApproximate || len(page.Errors) != 0 {
+		t.Errorf("total = %d (approximate %v), errors = %v", page.Total, page.TotalApproximate, page.Errors)
+	}
+
+	// Only tidal has more results; the next page asks it alone.
+	cursors, err := decodeFederatedCursor(page.NextCursor, f.providers)
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	if !reflect.DeepEqual(cursors, map[string]string{"tidal": "t2"}) {
+		t.Errorf("cursors = %v", cursors)
+	}
+	if _, err := f.Search(context.Background(), Query{Text: "pulse", Cursor: page.NextCursor}); err != nil {
+		t.Fatalf("Search next page: %v", err)
+	}
+	if first.calls.Load() != 1 || second.calls.Load() != 2 {
+		t.Errorf("calls = %d, %d; want 1, 2", first.calls.Load(), second.calls.Load())
+	}
+}
+
+func TestFederatedDegradesOnProviderFailure(t *testing.T) {
+	ok := &stubProvider{name: "amazon", search: func(ctx context.Context, q Query) (Page, error) {
+		return pageOf("a"), nil
+	}}
+	slow := &stubProvider{name: "slow", search: func(ctx context.Context, q Query) (Page, error) {
+		<-ctx.Done()
+		return Page{}, ctx.Err()
+	}}
+	broken := &stubProvider{name: "broken", search: func(ctx context.Context, q Query) (Page, error) {
+		return Page{}, errors.New("boom")
+	}}
+	f := NewFederated([]SearchProvider{ok, slow, broken}, 20*time.Millisecond)
+
+	page, err := f.Search(context.Background(), Query{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := []ProviderError{{Provider: "slow", Error: "timed out"}, {Provider: "broken", Error: "boom"}}
+	if !reflect.DeepEqual(page.Errors, want) {
+		t.Errorf("errors = %v, want %v", page.Errors, want)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("items = %v", page.Items)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("failed providers should not be asked again, next cursor = %q", page.NextCursor)
+	}
+	if page.TotalApproximate {
+		t.Error("total without merged duplicates should be exact")
+	}
+
+	f = NewFederated([]SearchProvider{broken}, time.Second)
+	if _, err := f.Search(context.Background(), Query{}); err == nil {
+		t.Error("expected an error when every provider fails")
+	}
+}
+
+func TestFederatedRejectsBadCursor(t *testing.T) {
+	f := NewFederated([]SearchProvider{&stubProvider{name: "amazon"}}, time.Second)
+	if _, err := f.Search(context.Background(), Query{Cursor: "not-a-cursor!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestMergeKey(t *testing.T) {
+	album := Result{Type: "album", Title: "Cities in Motion", Artist: "Pulse Runner"}
+	same := Result{Type: "album", Title: "x", AlbumTitle: "cities in motion", Artist: "PULSE RUNNER"}
+	song := Result{Type: "song", Title: "Cities in Motion", AlbumTitle: "Cities in Motion", Artist: "Pulse Runner"}
+	if mergeKey(album) != mergeKey(same) {
+		t.Error("albums differing only in case should merge")
+	}
+	if mergeKey(album) == mergeKey(song) {
+		t.Error("a song should not merge with its album")
+	}
+}
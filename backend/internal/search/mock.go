@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"fmt"
+)
+
+// MockProvider serves a fixed demo catalogue; used when no upstream is configured.
+type MockProvider struct{}
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// Search ignores free text, applies qualifiers and pages through the demo catalogue.
+func (p *MockProvider) Search(ctx context.Context, q Query) (Page, error) {
+	return paginate(mockCatalogue(), q)
+}
+
+// Album builds a synthetic tracklist for the demo albums.
+func (p *MockProvider) Album(ctx context.Context, id string) (*Album, error) {
+	for _, res := range mockCatalogue() {
+		if res.AlbumID != id {
+			continue
+		}
+		album := &Album{
+			ID:          res.AlbumID,
+			Title:       res.AlbumTitle,
+			Artist:      res.Artist,
+			ArtistID:    res.ArtistID,
+			CoverURL:    res.CoverURL,
+			ReleaseDate: fmt.Sprintf("%d-01-01", res.Year),
+			Label:       "Demo Records",
+			Source:      p.Name(),
+			Tracks:      make([]Track, 0, res.Tracks),
+		}
+		trackLen := res.Duration / res.Tracks
+		for n := 1; n <= res.Tracks; n++ {
+			title := fmt.Sprintf("Track %d", n)
+			if res.Type == "song" {
+				title = res.Title
+			}
+			album.Tracks = append(album.Tracks, Track{
+				ID:       fmt.Sprintf("%s_t%d", res.AlbumID, n),
+				Title:    title,
+				Number:   n,
+				Disc:     1,
+				Duration: trackLen,
+			})
+			album.Duration += trackLen
+		}
+		return album, nil
+	}
+	return nil, ErrNotFound
+}
+
+// ArtistAlbums returns the demo albums credited to artistID.
+func (p *MockProvider) ArtistAlbums(ctx context.Context, artistID string) (*Discography, error) {
+	var disco *Discography
+	seen := map[string]bool{}
+	for _, res := range mockCatalogue() {
+		if res.ArtistID != artistID || seen[res.AlbumID] {
+			continue
+		}
+		seen[res.AlbumID] = true
+		if res.Type == "song" {
+			// Singles show up in the discography as their parent release.
+			res.ID = res.AlbumID
+			res.Type = "album"
+			res.Title = res.AlbumTitle
+		}
+		if disco == nil {
+			disco = &Discography{ArtistID: artistID, Artist: res.Artist}
+		}
+		disco.Albums = append(disco.Albums, res)
+	}
+	if disco == nil {
+		return nil, ErrNotFound
+	}
+	return disco, nil
+}
+
+func mockCatalogue() []Result {
+	return []Result{
+		{
+			ID:         "alb_demo_1",
+			Type:       "album",
+			Title:      "Lights & Echoes",
+			Artist:     "Demo Ensemble",
+			ArtistID:   "art_demo_ensemble",
+			AlbumID:    "alb_demo_1",
+			AlbumTitle: "Lights & Echoes",
+			CoverURL:   "https://placehold.co/200x200?text=Album",
+			Tracks:     10,
+			Duration:   2300,
+			Year:       2022,
+		},
+		{
+			ID:         "alb_demo_single_parent",
+			Type:       "song",
+			Title:      "Silent Rivers",
+			Artist:     "Demo Ensemble",
+			ArtistID:   "art_demo_ensemble",
+			AlbumID:    "alb_demo_single_parent",
+			AlbumTitle: "Silent Rivers (Single)",
+			CoverURL:   "https://placehold.co/200x200?text=Single",
+			Tracks:     1,
+			Duration:   210,
+			Year:       2023,
+		},
+		{
+			ID:         "alb_electro_2024",
+			Type:       "album",
+			Title:      "Cities in Motion",
+			Artist:     "Pulse Runner",
+			ArtistID:   "art_pulse_runner",
+			AlbumID:    "alb_electro_2024",
+			AlbumTitle: "Cities in Motion",
+			CoverURL:   "https://placehold.co/200x200?text=Album",
+			Tracks:     12,
+			Duration:   2600,
+			Year:       2024,
+		},
+	}
+}
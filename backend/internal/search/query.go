@@ -0,0 +1,157 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"navidrome-helper/internal/util"
+)
+
+// SyntaxError reports a malformed search query.
+type SyntaxError struct {
+	Pos int // byte offset into the raw query
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+// qualifiers are the recognised key:value prefixes; anything else stays free text
+// so titles such as "Mission: Impossible" keep working.
+var qualifiers = map[string]struct{}{
+	"artist": {}, "album": {}, "year": {}, "type": {},
+}
+
+// ParseQuery splits raw into free text and qualifiers such as
+// artist:"Pulse Runner" album:cities year:2024 type:album.
+func ParseQuery(raw string) (Query, error) {
+	var q Query
+	var text []string
+	seen := map[string]bool{}
+	pos := 0
+	for pos < len(raw) {
+		if r, size := utf8.DecodeRuneInString(raw[pos:]); unicode.IsSpace(r) {
+			pos += size
+			continue
+		}
+		start := pos
+		key, value, next, err := scanToken(raw, pos)
+		if err != nil {
+			return Query{}, err
+		}
+		pos = next
+		if key == "" {
+			if value != "" {
+				text = append(text, value)
+			}
+			continue
+		}
+		if value == "" {
+			return Query{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("%s: needs a value", key)}
+		}
+		if seen[key] {
+			return Query{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("%s: given more than once", key)}
+		}
+		seen[key] = true
+		switch key {
+		case "artist":
+			q.Artist = value
+		case "album":
+			q.Album = value
+		case "year":
+			year, err := strconv.Atoi(value)
+			if err != nil || year < 1000 || year > 9999 {
+				return Query{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("year: %q is not a four digit year", value)}
+			}
+			q.Year = year
+		case "type":
+			value = strings.ToLower(value)
+			if value != "album" && value != "song" {
+				return Query{}, &SyntaxError{Pos: start, Msg: "type: must be album or song"}
+			}
+			q.Type = value
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// scanToken reads one whitespace-delimited token starting at pos. Known
+// qualifiers come back as key/value; everything else as free text in value.
+func scanToken(raw string, pos int) (key, value string, next int, err error) {
+	if colon := strings.IndexByte(raw[pos:], ':'); colon > 0 {
+		candidate := strings.ToLower(raw[pos : pos+colon])
+		if _, ok := qualifiers[candidate]; ok {
+			valuePos := pos + colon + 1
+			if valuePos < len(raw) && raw[valuePos] == '"' {
+				value, next, ok = scanQuoted(raw, valuePos)
+				if !ok {
+					return "", "", 0, &SyntaxError{Pos: valuePos, Msg: "unterminated quote"}
+				}
+				return candidate, value, next, nil
+			}
+			value, next = scanWord(raw, valuePos)
+			return candidate, value, next, nil
+		}
+	}
+	// A quoted phrase is one piece of free text; an unmatched quote is just
+	// part of the text.
+	if raw[pos] == '"' {
+		if value, next, ok := scanQuoted(raw, pos); ok {
+			return "", value, next, nil
+		}
+	}
+	value, next = scanWord(raw, pos)
+	return "", value, next, nil
+}
+
+// scanQuoted reads a double-quoted phrase starting at the quote at pos. It
+// reports false when the quote is never closed.
+func scanQuoted(raw string, pos int) (string, int, bool) {
+	end := strings.IndexByte(raw[pos+1:], '"')
+	if end < 0 {
+		return "", 0, false
+	}
+	return strings.TrimSpace(raw[pos+1 : pos+1+end]), pos + end + 2, true
+}
+
+// scanWord reads up to the next whitespace. Quotes inside it are literal.
+func scanWord(raw string, pos int) (string, int) {
+	end := pos
+	for end < len(raw) {
+		r, size := utf8.DecodeRuneInString(raw[end:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		end += size
+	}
+	return raw[pos:end], end
+}
+
+// Matches reports whether r satisfies the artist/album/year qualifiers of q.
+// Free text is left to the provider's own relevance matching.
+func (q Query) Matches(r Result) bool {
+	if q.Type != "" && r.Type != q.Type {
+		return false
+	}
+	if q.Artist != "" && !strings.Contains(util.NormalizeName(r.Artist), util.NormalizeName(q.Artist)) {
+		return false
+	}
+	if q.Album != "" {
+		album := r.AlbumTitle
+		if album == "" {
+			album = r.Title
+		}
+		if !strings.Contains(util.NormalizeName(album), util.NormalizeName(q.Album)) {
+			return false
+		}
+	}
+	if q.Year != 0 && r.Year != 0 && r.Year != q.Year {
+		return false
+	}
+	return true
+}
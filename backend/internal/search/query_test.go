@@ -0,0 +1,84 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Query
+	}{
+		{raw: "", want: Query{}},
+		{raw: "  cities in motion  ", want: Query{Text: "cities in motion"}},
+		{raw: `artist:"Pulse Runner" album:cities year:2024 type:Album`, want: Query{Artist: "Pulse Runner", Album: "cities", Year: 2024, Type: "album"}},
+		{raw: "ARTIST:pulse runner", want: Query{Artist: "pulse", Text: "runner"}},
+		{raw: "Mission: Impossible", want: Query{Text: "Mission: Impossible"}},
+		{raw: `"night drive" artist:pulse`, want: Query{Text: "night drive", Artist: "pulse"}},
+		{raw: `12" single`, want: Query{Text: `12" single`}},
+		{raw: `Hello "World`, want: Query{Text: `Hello "World`}},
+		{raw: `"`, want: Query{Text: `"`}},
+		{raw: `""`, want: Query{}},
+		{raw: "Ågård", want: Query{Text: "Ågård"}},
+		{raw: "à la mode", want: Query{Text: "à la mode"}},
+		{raw: "artist:Björk album:Homogenic", want: Query{Artist: "Björk", Album: "Homogenic"}},
+		{raw: "東京　事変", want: Query{Text: "東京 事変"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuery(tt.raw)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		raw string
+		pos int
+	}{
+		{raw: `artist:"Pulse Runner`, pos: 7},
+		{raw: "artist:", pos: 0},
+		{raw: "x artist:a artist:b", pos: 11},
+		{raw: "year:24", pos: 0},
+		{raw: "year:abcd", pos: 0},
+		{raw: "type:podcast", pos: 0},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.raw)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseQuery(%q) error = %v, want *SyntaxError", tt.raw, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("ParseQuery(%q) error at %d, want %d", tt.raw, syntaxErr.Pos, tt.pos)
+		}
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	r := Result{Type: "album", Title: "Cities in Motion", Artist: "Pulse Runner", Year: 2024}
+	tests := []struct {
+		q    Query
+		want bool
+	}{
+		{Query{}, true},
+		{Query{Artist: "pulse"}, true},
+		{Query{Artist: "PULSE RUNNER", Album: "cities"}, true},
+		{Query{Album: "nowhere"}, false},
+		{Query{Type: "song"}, false},
+		{Query{Year: 2023}, false},
+		{Query{Year: 2024}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Matches(r); got != tt.want {
+			t.Errorf("%+v.Matches = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+const (
+	// DefaultLimit is used when a query does not ask for a page size.
+	DefaultLimit = 25
+	// MaxLimit caps the page size a caller can request.
+	MaxLimit = 100
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor cannot be decoded by the provider.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrNotFound is returned when the provider does not know the requested id.
+	ErrNotFound = errors.New("not found")
+)
+
+// Result is a single album or song returned by a search provider.
+type Result struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"` // album|song
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	ArtistID   string `json:"artistId,omitempty"`
+	AlbumID    string `json:"albumId,omitempty"`
+	AlbumTitle string `json:"albumTitle,omitempty"`
+	CoverURL   string `json:"coverUrl"`
+	Tracks     int    `json:"tracks,omitempty"`
+	Duration   int    `json:"duration,omitempty"`
+	Year       int    `json:"year,omitempty"`
+	// Sources and Rank are filled in when several providers are federated.
+	Sources []string `json:"sources,omitempty"`
+	Rank    int      `json:"rank,omitempty"`
+}
+
+// Album is the full detail of a release including its tracklist.
+type Album struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist"`
+	ArtistID    string  `json:"artistId,omitempty"`
+	CoverURL    string  `json:"coverUrl"`
+	ReleaseDate string  `json:"releaseDate,omitempty"` // YYYY-MM-DD as reported upstream
+	Label       string  `json:"label,omitempty"`
+	Explicit    bool    `json:"explicit"`
+	Duration    int     `json:"duration,omitempty"`
+	Tracks      []Track `json:"tracks"`
+	Source      string  `json:"source,omitempty"`
+}
+
+// Track is one entry of an album tracklist.
+type Track struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Number   int    `json:"number"`
+	Disc     int    `json:"disc"`
+	Duration int    `json:"duration"`
+	Explicit bool   `json:"explicit"`
+}
+
+// Discography lists the releases of a single artist.
+type Discography struct {
+	ArtistID string   `json:"artistId"`
+	Artist   string   `json:"artist"`
+	Albums   []Result `json:"albums"`
+}
+
+// Query describes a single page request against a provider.
+type Query struct {
+	Text   string
+	Type   string // album|song, empty for both
+	Artist string // artist: qualifier
+	Album  string // album: qualifier
+	Year   int    // year: qualifier, 0 for any
+	Limit  int
+	Cursor string // opaque, taken from a previous Page.NextCursor
+}
+
+// Page is one page of provider results.
+type Page struct {
+	Items      []Result
+	Total      int
+	NextCursor string
+	// TotalApproximate is set when Total is an estimate, as for federated
+	// pages whose providers returned overlapping releases.
+	TotalApproximate bool
+	// Errors lists providers that could not contribute to a federated page.
+	Errors []ProviderError
+}
+
+// SearchProvider looks up albums and songs in an upstream catalogue.
+type SearchProvider interface {
+	// Name identifies the provider in logs and responses.
+	Name() string
+	// Search returns the page of results described by q.
+	Search(ctx context.Context, q Query) (Page, error)
+	// Album returns the detail for an album id, or ErrNotFound.
+	Album(ctx context.Context, id string) (*Album, error)
+	// ArtistAlbums returns every release of an artist id, or ErrNotFound.
+	ArtistAlbums(ctx context.Context, artistID string) (*Discography, error)
+}
+
+// paginate applies qualifier filtering and offset cursors to an in-memory result set.
+func paginate(items []Result, q Query) (Page, error) {
+	offset := 0
+	if q.Cursor != "" {
+		n, err := strconv.Atoi(q.Cursor)
+		if err != nil || n < 0 {
+			return Page{}, ErrInvalidCursor
+		}
+		offset = n
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	filtered := make([]Result, 0, len(items))
+	for _, it := range items {
+		if !q.Matches(it) {
+			continue
+		}
+		filtered = append(filtered, it)
+	}
+
+	page := Page{Items: []Result{}, Total: len(filtered)}
+	if offset >= len(filtered) {
+		return page, nil
+	}
+	end := offset + limit
+	if end < len(filtered) {
+		page.NextCursor = strconv.Itoa(end)
+	} else {
+		end = len(filtered)
+	}
+	page.Items = filtered[offset:end]
+	return page, nil
+}
@@ -3,7 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,45 +17,201 @@ import (
 	"navidrome-helper/internal/config"
 	"navidrome-helper/internal/jobs"
 	"navidrome-helper/internal/library"
+	"navidrome-helper/internal/search"
 	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/upstream"
 	"navidrome-helper/internal/util"
 )
 
 // Server wires HTTP handlers to the runner and store.
 type Server struct {
-	cfg    config.Config
-	store  *store.Store
-	runner *jobs.Runner
-	index  *library.Indexer
+	cfg      config.Config
+	store    *store.Store
+	runner   *jobs.Runner
+	index    *library.Indexer
+	provider search.SearchProvider
+	outbound *upstream.Transport
 }
 
-func New(cfg config.Config, store *store.Store, runner *jobs.Runner, indexer *library.Indexer) *Server {
-	return &Server{cfg: cfg, store: store, runner: runner, index: indexer}
+func New(cfg config.Config, store *store.Store, runner *jobs.Runner, indexer *library.Indexer, provider search.SearchProvider, outbound *upstream.Transport) *Server {
+	return &Server{cfg: cfg, store: store, runner: runner, index: indexer, provider: provider, outbound: outbound}
 }
 
 func (s *Server) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Use(corsMiddleware)
 
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-	})
+	r.Get("/health", s.handleHealth)
 
 	r.Get("/api/search", s.handleSearch)
+	r.Get("/api/albums/{id}", s.handleGetAlbum)
+	r.Get("/api/artists/{id}/albums", s.handleArtistAlbums)
 	r.Post("/api/import", s.handleImport)
 	r.Get("/api/jobs", s.handleListJobs)
 	r.Get("/api/jobs/{id}", s.handleGetJob)
+	r.Patch("/api/jobs/{id}", s.handleUpdateJob)
+	r.Post("/api/jobs/{id}/cancel", s.handleCancelJob)
+	r.Post("/api/jobs/{id}/retry", s.handleRetryJob)
+	r.Post("/api/jobs/{id}/confirm", s.handleConfirmJob)
+	r.Get("/api/queue", s.handleQueue)
+	r.Post("/api/queue/pause", s.handlePauseQueue)
+	r.Post("/api/queue/resume", s.handleResumeQueue)
+	r.Post("/api/jobs/{id}/pause", s.handlePauseJob)
+	r.Post("/api/jobs/{id}/resume", s.handleResumeJob)
 	r.Get("/api/library", s.handleLibraryList)
 	r.Post("/api/library/refresh", s.handleLibraryRefresh)
+	r.Post("/api/library/match", s.handleLibraryMatch)
 
 	return r
 }
 
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"status": "ok", "queuePaused": s.runner.Paused()}
+	if c, ok := s.provider.(*search.Cache); ok {
+		resp["searchCache"] = c.Stats()
+	}
+	if s.outbound != nil {
+		resp["upstream"] = s.outbound.Breakers()
+	}
+	resp["disk"] = map[string]any{
+		"reserveBytes": s.cfg.DiskReserve,
+		"temp":         diskStatus(s.cfg.TempDir),
+		"music":        diskStatus(s.cfg.NavidromePath),
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// diskStatus reports the free space on the filesystem holding path.
+func diskStatus(path string) map[string]any {
+	status := map[string]any{"path": path}
+	free, err := util.FreeSpace(path)
+	if err != nil {
+		status["error"] = err.Error()
+		return status
+	}
+	status["freeBytes"] = free
+	return status
+}
+
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	results := mockSearchResults(query)
-	s.annotateExists(results)
-	writeJSON(w, http.StatusOK, map[string]any{"items": results})
+	q, err := parseSearchQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	page, err := s.provider.Search(ctx, q)
+	if errors.Is(err, search.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("search via %s failed: %v", s.provider.Name(), err)
+		http.Error(w, "search failed", http.StatusBadGateway)
+		return
+	}
+	results := make([]searchResult, 0, len(page.Items))
+	for _, res := range page.Items {
+		results = append(results, searchResult{Result: res})
+	}
+	s.annotateMatches(results)
+	writeJSON(w, http.StatusOK, searchResponse{Items: results, Total: page.Total, TotalApproximate: page.TotalApproximate, NextCursor: page.NextCursor, Degraded: page.Errors})
+}
+
+func (s *Server) handleGetAlbum(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	album, err := s.provider.Album(ctx, id)
+	if errors.Is(err, search.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("album %s via %s failed: %v", id, s.provider.Name(), err)
+		http.Error(w, "failed to fetch album", http.StatusBadGateway)
+		return
+	}
+	matches, err := library.MatchBatch(s.store, []library.MatchQuery{{Artist: album.Artist, Album: album.Title, Tracks: len(album.Tracks)}})
+	if err != nil {
+		http.Error(w, "failed to check library", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, albumDetail{Album: *album, Match: matches[0]})
+}
+
+func (s *Server) handleArtistAlbums(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	disco, err := s.provider.ArtistAlbums(ctx, id)
+	if errors.Is(err, search.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("artist %s via %s failed: %v", id, s.provider.Name(), err)
+		http.Error(w, "failed to fetch discography", http.StatusBadGateway)
+		return
+	}
+	queries := make([]library.MatchQuery, len(disco.Albums))
+	for idx, res := range disco.Albums {
+		title := res.AlbumTitle
+		if title == "" {
+			title = res.Title
+		}
+		queries[idx] = library.MatchQuery{Artist: disco.Artist, Album: title, Tracks: res.Tracks}
+	}
+	matches, err := library.MatchBatch(s.store, queries)
+	if err != nil {
+		http.Error(w, "failed to check library", http.StatusInternalServerError)
+		return
+	}
+
+	resp := discographyResponse{ArtistID: disco.ArtistID, Artist: disco.Artist, Albums: make([]discographyAlbum, 0, len(disco.Albums))}
+	for idx, res := range disco.Albums {
+		item := discographyAlbum{Result: res, Match: matches[idx], Status: libraryStatus(matches[idx], res.Tracks)}
+		switch item.Status {
+		case libraryPresent:
+			resp.Summary.Present++
+		case libraryPartial:
+			resp.Summary.Partial++
+		default:
+			resp.Summary.Missing++
+		}
+		resp.Albums = append(resp.Albums, item)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchQuery validates the q/limit/cursor/type parameters of /api/search.
+// q may carry qualifiers (artist:, album:, year:, type:) alongside free text.
+func parseSearchQuery(r *http.Request) (search.Query, error) {
+	params := r.URL.Query()
+	q, err := search.ParseQuery(params.Get("q"))
+	if err != nil {
+		return q, err
+	}
+	q.Limit = search.DefaultLimit
+	q.Cursor = params.Get("cursor")
+	if typ := strings.ToLower(strings.TrimSpace(params.Get("type"))); typ != "" {
+		if typ != "album" && typ != "song" {
+			return q, fmt.Errorf("type must be album or song")
+		}
+		if q.Type != "" && q.Type != typ {
+			return q, fmt.Errorf("type=%s conflicts with type:%s in q", typ, q.Type)
+		}
+		q.Type = typ
+	}
+	if raw := params.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > search.MaxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", search.MaxLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
 }
 
 func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
@@ -64,8 +224,13 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no items provided", http.StatusBadRequest)
 		return
 	}
-
+	if req.Priority < -maxPriority || req.Priority > maxPriority {
+		http.Error(w, fmt.Sprintf("priority must be between %d and %d", -maxPriority, maxPriority), http.StatusBadRequest)
+		return
+	}
+	// Items keep their request order; the runner processes them in turn.
 	dedup := map[string]importItem{}
+	var order []string
 	for _, it := range req.Items {
 		if it.Type == "" {
 			it.Type = "album"
@@ -83,18 +248,21 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 				AlbumTitle: it.AlbumTitle,
 				CoverURL:   it.CoverURL,
 			}
+			order = append(order, it.AlbumID)
 			continue
 		}
 		if _, ok := dedup[it.ID]; ok {
 			continue
 		}
 		dedup[it.ID] = it
+		order = append(order, it.ID)
 	}
 
 	var items []store.JobItem
 	artist := ""
 	album := ""
-	for _, v := range dedup {
+	for _, id := range order {
+		v := dedup[id]
 		if artist == "" {
 			artist = v.Artist
 		}
@@ -122,14 +290,63 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		Album:    album,
 		Progress: 0,
 		Items:    items,
+
+		MaxAttempts: max(s.cfg.JobMaxAttempts, 1),
+		Priority:    req.Priority,
+	}
+	if req.RunAt != nil && req.RunAt.After(time.Now()) {
+		job.RunAt = req.RunAt
+		job.Status = jobs.StatusScheduled
+		job.Message = fmt.Sprintf("Scheduled for %s", req.RunAt.Local().Format(time.RFC3339))
+	}
+	if req.DryRun {
+		job.Status, job.Phase, job.Message = jobs.StatusPlanned, jobs.PhasePlanned, "Planning"
 	}
 
-	if err := s.store.InsertJob(job); err != nil {
+	// A dry run queues nothing; the queue is checked when it is confirmed.
+	if req.DryRun {
+		if err := s.store.InsertJob(job); err != nil {
+			http.Error(w, "failed to create job", http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), planTimeout)
+		defer cancel()
+		plan, err := s.runner.Plan(ctx, job)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			http.Error(w, "planning the import timed out", http.StatusGatewayTimeout)
+			return
+		case errors.Is(err, context.Canceled):
+			// The client went away; nobody reads the response.
+			w.WriteHeader(statusClientClosedRequest)
+			return
+		case err != nil:
+			http.Error(w, "failed to plan import", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"jobId": job.ID, "dryRun": true, "plan": plan})
+		return
+	}
+
+	dups, err := s.runner.Submit(job)
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			queueFull(w)
+			return
+		}
 		http.Error(w, "failed to create job", http.StatusInternalServerError)
 		return
 	}
-	s.runner.Enqueue(job)
-	writeJSON(w, http.StatusAccepted, map[string]string{"jobId": job.ID})
+	if len(job.Items) == 0 {
+		// Everything is already being imported; point at the existing job.
+		writeJSON(w, http.StatusOK, map[string]any{"jobId": dups[0].JobID, "duplicates": dups})
+		return
+	}
+	resp := map[string]any{"jobId": job.ID}
+	if len(dups) > 0 {
+		resp["duplicates"] = dups
+	}
+	writeJSON(w, http.StatusAccepted, resp)
 }
 
 func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
@@ -155,6 +372,212 @@ func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, job)
 }
 
+type updateJobRequest struct {
+	Priority *int `json:"priority"`
+}
+
+func (s *Server) handleUpdateJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req updateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.Priority == nil {
+		http.Error(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+	if *req.Priority < -maxPriority || *req.Priority > maxPriority {
+		http.Error(w, fmt.Sprintf("priority must be between %d and %d", -maxPriority, maxPriority), http.StatusBadRequest)
+		return
+	}
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.runner.SetPriority(id, *req.Priority); err != nil {
+		if errors.Is(err, jobs.ErrJobFinished) {
+			http.Error(w, "job already finished", http.StatusConflict)
+			return
+		}
+		http.Error(w, "failed to update job", http.StatusInternalServerError)
+		return
+	}
+	if job, err = s.store.GetJob(id); err != nil || job == nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	view, err := s.runner.Queue(time.Now())
+	if err != nil {
+		http.Error(w, "failed to load queue", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handlePauseQueue(w http.ResponseWriter, r *http.Request) {
+	if err := s.runner.Pause(); err != nil {
+		http.Error(w, "failed to pause queue", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Server) handleResumeQueue(w http.ResponseWriter, r *http.Request) {
+	if err := s.runner.Resume(); err != nil {
+		http.Error(w, "failed to resume queue", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func (s *Server) handlePauseJob(w http.ResponseWriter, r *http.Request) {
+	s.changeJob(w, r, s.runner.PauseJob, jobs.ErrNotPausable)
+}
+
+func (s *Server) handleResumeJob(w http.ResponseWriter, r *http.Request) {
+	s.changeJob(w, r, s.runner.ResumeJob, jobs.ErrNotPaused)
+}
+
+// changeJob applies a runner action to the job in the URL and answers with the
+// updated job; conflict errors map to 409.
+func (s *Server) changeJob(w http.ResponseWriter, r *http.Request, action func(id string) error, conflict error) {
+	id := chi.URLParam(r, "id")
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := action(id); err != nil {
+		if errors.Is(err, conflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if duplicates(w, err) {
+			return
+		}
+		http.Error(w, "failed to update job", http.StatusInternalServerError)
+		return
+	}
+	if job, err = s.store.GetJob(id); err != nil || job == nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.runner.Cancel(id); err != nil {
+		if errors.Is(err, jobs.ErrJobFinished) {
+			http.Error(w, "job already finished", http.StatusConflict)
+			return
+		}
+		http.Error(w, "failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"jobId": id, "status": jobs.StatusCancelled})
+}
+
+func (s *Server) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.runner.Retry(id); err != nil {
+		if errors.Is(err, jobs.ErrNotRetryable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if duplicates(w, err) {
+			return
+		}
+		http.Error(w, "failed to retry job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"jobId": id, "status": jobs.StatusQueued})
+}
+
+// handleConfirmJob queues a planned dry run and answers with the job. When
+// active jobs cover every item by now, the plan is cancelled and the job that
+// is already importing them is returned instead.
+func (s *Server) handleConfirmJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	dups, err := s.runner.Confirm(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrNotPlanned):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, jobs.ErrQueueFull):
+			queueFull(w)
+		default:
+			http.Error(w, "failed to update job", http.StatusInternalServerError)
+		}
+		return
+	}
+	if len(dups) > 0 && len(dups) == len(job.Items) {
+		id = dups[0].JobID
+	}
+	if job, err = s.store.GetJob(id); err != nil || job == nil {
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// duplicates answers 409 with the covering jobs when err is a
+// *jobs.DuplicateError, and reports whether it did.
+func duplicates(w http.ResponseWriter, err error) bool {
+	var dup *jobs.DuplicateError
+	if !errors.As(err, &dup) {
+		return false
+	}
+	writeJSON(w, http.StatusConflict, map[string]any{"error": dup.Error(), "duplicates": dup.Duplicates})
+	return true
+}
+
+func queueFull(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(queueFullRetryAfter.Seconds())))
+	http.Error(w, "job queue is full, try again later", http.StatusServiceUnavailable)
+}
+
 func (s *Server) handleLibraryList(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Query().Get("refresh") == "true" && s.index != nil {
 		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
@@ -187,8 +610,64 @@ func (s *Server) handleLibraryRefresh(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"library": entries})
 }
 
+func (s *Server) handleLibraryMatch(w http.ResponseWriter, r *http.Request) {
+	var req libraryMatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		http.Error(w, "no items provided", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) > maxMatchItems {
+		http.Error(w, fmt.Sprintf("at most %d items per request", maxMatchItems), http.StatusBadRequest)
+		return
+	}
+	matches, err := library.MatchBatch(s.store, req.Items)
+	if err != nil {
+		http.Error(w, "failed to match library", http.StatusInternalServerError)
+		return
+	}
+	out := make([]libraryMatchResult, len(req.Items))
+	for idx, it := range req.Items {
+		out[idx] = libraryMatchResult{MatchQuery: it, Match: matches[idx]}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"matches": out})
+}
+
+// maxPriority bounds job priorities in both directions.
+const maxPriority = 100
+
+// queueFullRetryAfter is the Retry-After hint sent when QUEUE_MAX_DEPTH is reached.
+const queueFullRetryAfter = 30 * time.Second
+
+// planTimeout bounds a dry run, which resolves every item inside the request.
+const planTimeout = 2 * time.Minute
+
+// statusClientClosedRequest is logged when the client gives up on a dry run.
+const statusClientClosedRequest = 499
+
+// maxMatchItems bounds POST /api/library/match to keep the IN query reasonable.
+const maxMatchItems = 500
+
+type libraryMatchRequest struct {
+	Items []library.MatchQuery `json:"items"`
+}
+
+type libraryMatchResult struct {
+	library.MatchQuery
+	Match library.Match `json:"match"`
+}
+
 type importRequest struct {
 	Items []importItem `json:"items"`
+	// Priority orders the job in the queue; higher runs first (default 0).
+	Priority int `json:"priority"`
+	// RunAt schedules the job for later (RFC 3339); omitted or past means now.
+	RunAt *time.Time `json:"runAt"`
+	// DryRun plans the import without running it; see POST /api/jobs/{id}/confirm.
+	DryRun bool `json:"dryRun"`
 }
 
 type importItem struct {
@@ -201,79 +680,91 @@ type importItem struct {
 	CoverURL   string `json:"coverUrl"`
 }
 
+type searchResponse struct {
+	Items []searchResult `json:"items"`
+	Total int            `json:"total"`
+	// TotalApproximate is set when total is an estimate across overlapping providers.
+	TotalApproximate bool   `json:"totalApproximate,omitempty"`
+	NextCursor       string `json:"nextCursor,omitempty"`
+	// Degraded lists providers that failed or timed out for this page.
+	Degraded []search.ProviderError `json:"degraded,omitempty"`
+}
+
+// searchResult decorates a provider result with library state.
 type searchResult struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Title      string `json:"title"`
-	Artist     string `json:"artist"`
-	AlbumID    string `json:"albumId,omitempty"`
-	AlbumTitle string `json:"albumTitle,omitempty"`
-	CoverURL   string `json:"coverUrl"`
-	Tracks     int    `json:"tracks,omitempty"`
-	Duration   int    `json:"duration,omitempty"`
-	Exists     bool   `json:"exists"`
+	search.Result
+	Match library.Match `json:"match"`
 }
 
-func mockSearchResults(query string) []searchResult {
-	q := strings.TrimSpace(strings.ToLower(query))
-	if q == "" || strings.Contains("demo", q) || len(q) >= 0 {
-		return []searchResult{
-			{
-				ID:         "alb_demo_1",
-				Type:       "album",
-				Title:      "Lights & Echoes",
-				Artist:     "Demo Ensemble",
-				AlbumID:    "alb_demo_1",
-				AlbumTitle: "Lights & Echoes",
-				CoverURL:   "https://placehold.co/200x200?text=Album",
-				Tracks:     10,
-				Duration:   2300,
-			},
-			{
-				ID:         "alb_demo_single_parent",
-				Type:       "song",
-				Title:      "Silent Rivers",
-				Artist:     "Demo Ensemble",
-				AlbumID:    "alb_demo_single_parent",
-				AlbumTitle: "Silent Rivers (Single)",
-				CoverURL:   "https://placehold.co/200x200?text=Single",
-				Tracks:     1,
-				Duration:   210,
-			},
-			{
-				ID:         "alb_electro_2024",
-				Type:       "album",
-				Title:      "Cities in Motion",
-				Artist:     "Pulse Runner",
-				AlbumID:    "alb_electro_2024",
-				AlbumTitle: "Cities in Motion",
-				CoverURL:   "https://placehold.co/200x200?text=Album",
-				Tracks:     12,
-				Duration:   2600,
-			},
+// albumDetail is an album tracklist plus its library match.
+type albumDetail struct {
+	search.Album
+	Match library.Match `json:"match"`
+}
+
+const (
+	libraryPresent = "present"
+	libraryPartial = "partial"
+	libraryMissing = "missing"
+)
+
+type discographyResponse struct {
+	ArtistID string             `json:"artistId"`
+	Artist   string             `json:"artist"`
+	Albums   []discographyAlbum `json:"albums"`
+	Summary  struct {
+		Present int `json:"present"`
+		Partial int `json:"partial"`
+		Missing int `json:"missing"`
+	} `json:"summary"`
+}
+
+// discographyAlbum is a release with its library status (present|partial|missing).
+type discographyAlbum struct {
+	search.Result
+	Status string        `json:"status"`
+	Match  library.Match `json:"match"`
+}
+
+// libraryStatus maps a library match of a release with tracks tracks (0 when
+// unknown) to present, partial or missing. Another edition counts as present
+// only when it has as many tracks; a standard edition does not cover a longer
+// deluxe release.
+func libraryStatus(m library.Match, tracks int) string {
+	switch m.Status {
+	case library.MatchExact:
+		return libraryPresent
+	case library.MatchFuzzy:
+		if tracks > 0 && m.LocalTracks < tracks {
+			return libraryPartial
 		}
+		return libraryPresent
+	case library.MatchIncomplete:
+		return libraryPartial
 	}
-	return []searchResult{}
+	return libraryMissing
 }
 
-func (s *Server) annotateExists(results []searchResult) {
-	for idx := range results {
-		res := &results[idx]
-		artist := util.NormalizeName(res.Artist)
-		album := util.NormalizeName(res.Title)
+// annotateMatches attaches the library match for each result; songs match on their parent album.
+func (s *Server) annotateMatches(results []searchResult) {
+	queries := make([]library.MatchQuery, len(results))
+	for idx, res := range results {
+		album := res.Title
 		if res.Type == "song" && res.AlbumTitle != "" {
-			album = util.NormalizeName(res.AlbumTitle)
+			album = res.AlbumTitle
 		}
-		if artist == "" || album == "" {
-			res.Exists = false
-			continue
-		}
-		ok, err := s.store.LibraryExists(artist, album)
-		if err != nil {
-			res.Exists = false
-			continue
+		queries[idx] = library.MatchQuery{Artist: res.Artist, Album: album, Tracks: res.Tracks}
+	}
+	matches, err := library.MatchBatch(s.store, queries)
+	if err != nil {
+		log.Printf("annotate search results: %v", err)
+		for idx := range results {
+			results[idx].Match = library.Match{Status: library.MatchNone}
 		}
-		res.Exists = ok
+		return
+	}
+	for idx := range results {
+		results[idx].Match = matches[idx]
 	}
 }
 
@@ -286,7 +777,7 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PATCH,OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
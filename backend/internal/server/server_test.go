@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"navidrome-helper/internal/config"
+	"navidrome-helper/internal/jobs"
+	"navidrome-helper/internal/library"
+	"navidrome-helper/internal/search"
+	"navidrome-helper/internal/store"
+)
+
+// newTestServer returns the API of a server backed by a fresh database and
+// the mock search provider. The runner's workers are not started.
+func newTestServer(t *testing.T, cfg config.Config) (http.Handler, *Server) {
+	t.Helper()
+	dir := t.TempDir()
+	st, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TempDir = filepath.Join(dir, "tmp")
+	cfg.NavidromePath = filepath.Join(dir, "music")
+	s := New(cfg, st, jobs.NewRunner(st, cfg, nil), nil, search.NewMockProvider(), nil)
+	return s.Routes(), s
+}
+
+// do sends a request with an optional JSON body to h and returns the recorded response.
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLibraryStatus(t *testing.T) {
+	tests := []struct {
+		match  library.Match
+		tracks int
+		want   string
+	}{
+		{library.Match{Status: library.MatchExact, LocalTracks: 10}, 10, libraryPresent},
+		{library.Match{Status: library.MatchIncomplete, LocalTracks: 8}, 10, libraryPartial},
+		{library.Match{Status: library.MatchFuzzy, LocalTracks: 10}, 18, libraryPartial},
+		{library.Match{Status: library.MatchFuzzy, LocalTracks: 18}, 10, libraryPresent},
+		{library.Match{Status: library.MatchFuzzy, LocalTracks: 10}, 0, libraryPresent},
+		{library.Match{Status: library.MatchNone}, 10, libraryMissing},
+	}
+	for _, tt := range tests {
+		if got := libraryStatus(tt.match, tt.tracks); got != tt.want {
+			t.Errorf("libraryStatus(%+v, %d) = %q, want %q", tt.match, tt.tracks, got, tt.want)
+		}
+	}
+}
+
+func TestImportQueueFull(t *testing.T) {
+	h, _ := newTestServer(t, config.Config{QueueMaxDepth: 1, JobMaxAttempts: 1})
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"items":[{"id":"a1","title":"First","artist":"A"}]}`, http.StatusAccepted},
+		{`{"items":[{"id":"a2","title":"Second","artist":"A"}]}`, http.StatusServiceUnavailable},
+		// An album that is already queued points at its job rather than queueing.
+		{`{"items":[{"id":"a1","title":"First","artist":"A"}]}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := do(h, http.MethodPost, "/api/import", tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("POST %s = %d %s, want %d", tt.body, rec.Code, rec.Body, tt.want)
+			continue
+		}
+		if tt.want == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "30" {
+			t.Errorf("Retry-After = %q, want 30", rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	h, _ := newTestServer(t, config.Config{})
+
+	// Follow nextCursor one result at a time through the whole catalogue.
+	seen := map[string]bool{}
+	total := -1
+	target := "/api/search?q=a&limit=1"
+	for pages := 0; target != ""; pages++ {
+		if pages > 10 {
+			t.Fatal("nextCursor never ran out")
+		}
+		rec := do(h, http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", target, rec.Code, rec.Body)
+		}
+		var resp searchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Items) != 1 {
+			t.Fatalf("GET %s returned %d items, want 1", target, len(resp.Items))
+		}
+		if seen[resp.Items[0].ID] {
+			t.Errorf("%s came back twice", resp.Items[0].ID)
+		}
+		seen[resp.Items[0].ID] = true
+		total = resp.Total
+		target = ""
+		if resp.NextCursor != "" {
+			target = "/api/search?q=a&limit=1&cursor=" + resp.NextCursor
+		}
+	}
+	if len(seen) != total || total < 2 {
+		t.Errorf("paged through %d results, total = %d", len(seen), total)
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/search?q=a&cursor=not-a-cursor", http.StatusBadRequest},
+		{"/api/search?q=a&limit=0", http.StatusBadRequest},
+		{"/api/search?q=a&limit=1000", http.StatusBadRequest},
+		{"/api/search?q=a&type=video", http.StatusBadRequest},
+		{"/api/search?q=a&type=album", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := do(h, http.MethodGet, tt.target, ""); rec.Code != tt.want {
+			t.Errorf("GET %s = %d %s, want %d", tt.target, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestGetAlbum(t *testing.T) {
+	h, _ := newTestServer(t, config.Config{})
+	rec := do(h, http.MethodGet, "/api/albums/alb_demo_1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET album = %d %s", rec.Code, rec.Body)
+	}
+	var album albumDetail
+	if err := json.Unmarshal(rec.Body.Bytes(), &album); err != nil {
+		t.Fatal(err)
+	}
+	if album.ID != "alb_demo_1" || len(album.Tracks) == 0 || album.Match.Status != library.MatchNone {
+		t.Errorf("album = %s with %d tracks, match %q", album.ID, len(album.Tracks), album.Match.Status)
+	}
+
+	if rec := do(h, http.MethodGet, "/api/albums/no-such-album", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown album = %d, want 404", rec.Code)
+	}
+	if rec := do(h, http.MethodGet, "/api/artists/no-such-artist/albums", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown artist = %d, want 404", rec.Code)
+	}
+}
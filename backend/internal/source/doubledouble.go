@@ -0,0 +1,95 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DoubleDoubleResolver asks a doubledouble.top style service for a pixeldrain link.
+// The service is asked to prepare the album and polled until the link is ready.
+type DoubleDoubleResolver struct {
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewDoubleDoubleResolver returns a resolver rooted at baseURL (RESOLVER_BASE_URL).
+func NewDoubleDoubleResolver(baseURL string, client *http.Client) *DoubleDoubleResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DoubleDoubleResolver{baseURL: strings.TrimRight(baseURL, "/"), client: client, interval: 3 * time.Second}
+}
+
+type ddResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // queued|processing|done|error
+	URL    string `json:"url"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Error  string `json:"error"`
+}
+
+// Resolve requests GET {base}/dl?service=amazon&id= and polls GET {base}/dl/{id} until done.
+func (d *DoubleDoubleResolver) Resolve(ctx context.Context, req Request) (*Link, error) {
+	params := url.Values{}
+	params.Set("service", "amazon")
+	params.Set("id", req.SourceID)
+	var resp ddResponse
+	if err := d.get(ctx, "/dl?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	for {
+		switch resp.Status {
+		case "done":
+			if resp.URL == "" {
+				return nil, fmt.Errorf("resolve %s: %w: empty link", req.SourceID, ErrUnavailable)
+			}
+			return &Link{URL: resp.URL, FileName: resp.Name, Size: resp.Size}, nil
+		case "error":
+			return nil, fmt.Errorf("resolve %s: %w: %s", req.SourceID, ErrUnavailable, resp.Error)
+		}
+		if resp.ID == "" {
+			return nil, fmt.Errorf("resolve %s: unexpected status %q", req.SourceID, resp.Status)
+		}
+		if err := sleep(ctx, d.interval); err != nil {
+			return nil, err
+		}
+		id := resp.ID
+		resp = ddResponse{}
+		if err := d.get(ctx, "/dl/"+url.PathEscape(id), &resp); err != nil {
+			return nil, err
+		}
+		if resp.ID == "" {
+			resp.ID = id
+		}
+	}
+}
+
+func (d *DoubleDoubleResolver) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("build resolver request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("resolver request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("resolver request: %w", ErrUnavailable)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("resolver request: unexpected status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode resolver response: %w", err)
+	}
+	return nil
+}
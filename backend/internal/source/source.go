@@ -0,0 +1,72 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnavailable is returned when the resolver cannot produce a download for an item.
+var ErrUnavailable = errors.New("source unavailable")
+
+// Request identifies the album to resolve.
+type Request struct {
+	SourceID string
+	Artist   string
+	Album    string
+}
+
+// Link is a resolved download for an album archive.
+type Link struct {
+	URL      string `json:"url"`
+	FileName string `json:"fileName,omitempty"`
+	Size     int64  `json:"size,omitempty"` // bytes, 0 when unknown
+}
+
+// Resolver turns a catalogue id into a downloadable archive link.
+type Resolver interface {
+	Resolve(ctx context.Context, req Request) (*Link, error)
+}
+
+// Downloader fetches a resolved link into dest and returns the bytes written.
+type Downloader interface {
+	Download(ctx context.Context, link *Link, dest string) (int64, error)
+}
+
+// Sizer is implemented by downloaders that can report a link's size without
+// downloading it.
+type Sizer interface {
+	Size(ctx context.Context, link *Link) (int64, error)
+}
+
+// StubResolver stands in for doubledouble.top while ENABLE_DOWNLOADS is off.
+type StubResolver struct {
+	Delay time.Duration
+}
+
+func (s StubResolver) Resolve(ctx context.Context, req Request) (*Link, error) {
+	if err := sleep(ctx, s.Delay); err != nil {
+		return nil, err
+	}
+	return &Link{URL: "stub://" + req.SourceID, FileName: req.SourceID + ".zip"}, nil
+}
+
+// StubDownloader pretends to download and writes nothing.
+type StubDownloader struct {
+	Delay time.Duration
+}
+
+func (s StubDownloader) Download(ctx context.Context, link *Link, dest string) (int64, error) {
+	return 0, sleep(ctx, s.Delay)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
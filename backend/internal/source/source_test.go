@@ -0,0 +1,123 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoubleDoubleResolverPolls(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dl":
+			if r.URL.Query().Get("id") != "alb1" || r.URL.Query().Get("service") != "amazon" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"id": "job9", "status": "queued"}`))
+		case "/dl/job9":
+			if polls.Add(1) < 2 {
+				_, _ = w.Write([]byte(`{"status": "processing"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "done", "url": "https://pixeldrain.com/u/abc", "name": "alb1.zip", "size": 42}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	d := NewDoubleDoubleResolver(srv.URL+"/", nil)
+	d.interval = time.Millisecond
+
+	link, err := d.Resolve(context.Background(), Request{SourceID: "alb1"})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if link.URL != "https://pixeldrain.com/u/abc" || link.FileName != "alb1.zip" || link.Size != 42 || polls.Load() != 2 {
+		t.Errorf("link = %+v after %d polls", link, polls.Load())
+	}
+}
+
+func TestDoubleDoubleResolverErrors(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		body   string
+		want   error
+	}{
+		"upstream error": {status: http.StatusOK, body: `{"status": "error", "error": "region locked"}`, want: ErrUnavailable},
+		"empty link":     {status: http.StatusOK, body: `{"status": "done"}`, want: ErrUnavailable},
+		"not found":      {status: http.StatusNotFound, want: ErrUnavailable},
+		"server error":   {status: http.StatusBadGateway},
+		"no job id":      {status: http.StatusOK, body: `{"status": "queued"}`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			_, err := NewDoubleDoubleResolver(srv.URL, nil).Resolve(context.Background(), Request{SourceID: "alb1"})
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPixeldrainDownloader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "7")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("zipdata"))
+		}
+	}))
+	defer srv.Close()
+	p := NewPixeldrainDownloader(nil)
+	dest := filepath.Join(t.TempDir(), "alb1.zip")
+
+	n, err := p.Download(context.Background(), &Link{URL: srv.URL + "/file"}, dest)
+	if err != nil || n != 7 {
+		t.Fatalf("Download = %d, %v", n, err)
+	}
+	if body, _ := os.ReadFile(dest); string(body) != "zipdata" {
+		t.Errorf("downloaded %q", body)
+	}
+	if size, err := p.Size(context.Background(), &Link{URL: srv.URL + "/file"}); err != nil || size != 7 {
+		t.Errorf("Size = %d, %v", size, err)
+	}
+
+	// A failed download leaves neither the file nor its .part behind.
+	failed := filepath.Join(t.TempDir(), "alb2.zip")
+	if _, err := p.Download(context.Background(), &Link{URL: srv.URL + "/missing"}, failed); err == nil {
+		t.Error("404 download succeeded")
+	}
+	for _, path := range []string{failed, failed + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", path)
+		}
+	}
+}
+
+func TestPixeldrainFileURL(t *testing.T) {
+	tests := map[string]string{
+		"https://pixeldrain.com/u/abc123":        "https://pixeldrain.com/api/file/abc123?download",
+		"https://pixeldrain.com/api/file/abc123": "https://pixeldrain.com/api/file/abc123",
+		"https://example.com/u/abc123":           "https://example.com/u/abc123",
+	}
+	for in, want := range tests {
+		if got := pixeldrainFileURL(in); got != want {
+			t.Errorf("pixeldrainFileURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
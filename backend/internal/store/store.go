@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -11,18 +12,31 @@ import (
 
 // Job represents a single import job persisted to storage.
 type Job struct {
-	ID         string       `json:"id"`
-	Status     string       `json:"status"`
-	Phase      string       `json:"phase"`
-	Message    string       `json:"message"`
-	Progress   float64      `json:"progress"`
-	Artist     string       `json:"artist"`
-	Album      string       `json:"album"`
-	CreatedAt  time.Time    `json:"createdAt"`
-	UpdatedAt  time.Time    `json:"updatedAt"`
-	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
-	Items      []JobItem    `json:"items,omitempty"`
-	Logs       []JobLogLine `json:"logs,omitempty"`
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Phase      string     `json:"phase"`
+	Message    string     `json:"message"`
+	Progress   float64    `json:"progress"`
+	Artist     string     `json:"artist"`
+	Album      string     `json:"album"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// StartedAt is when a worker last claimed the job.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// Priority orders the queue: higher runs first, ties by creation time.
+	Priority int `json:"priority"`
+	// RunAt holds a scheduled job back until the given time.
+	RunAt *time.Time `json:"runAt,omitempty"`
+	// Attempts counts failed runs; failures below MaxAttempts are retried
+	// automatically at NextRetryAt.
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"maxAttempts"`
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"`
+	// ErrorCode classifies the last failure, e.g. "timeout"; empty otherwise.
+	ErrorCode string       `json:"errorCode,omitempty"`
+	Items     []JobItem    `json:"items,omitempty"`
+	Logs      []JobLogLine `json:"logs,omitempty"`
 }
 
 // JobItem records each source item that maps to the job.
@@ -38,6 +52,17 @@ type JobItem struct {
 	Message    string    `json:"message"`
 	CreatedAt  time.Time `json:"createdAt"`
 	UpdatedAt  time.Time `json:"updatedAt"`
+	// Checkpoint is the last pipeline phase the item completed; used to resume after a restart.
+	Checkpoint string `json:"checkpoint,omitempty"`
+	SourceURL  string `json:"sourceUrl,omitempty"`
+	// TargetPath, Conflict and EstimatedSize (bytes, 0 when unknown) are
+	// filled in by a dry run.
+	TargetPath    string `json:"targetPath,omitempty"`
+	Conflict      string `json:"conflict,omitempty"`
+	EstimatedSize int64  `json:"estimatedSize,omitempty"`
+	// Skipped records that the album folder already existed when the item
+	// reached placing, so nothing was placed.
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 // JobLogLine captures a message tied to a timestamp.
@@ -49,13 +74,13 @@ type JobLogLine struct {
 
 // LibraryEntry represents an album indexed from NAVIDROME_MUSIC_PATH.
 type LibraryEntry struct {
-	Artist      string    `json:"artist"`
-	Album       string    `json:"album"`
-	Path        string    `json:"path"`
-	TrackCount  int       `json:"trackCount"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	ArtistNorm  string    `json:"-"`
-	AlbumNorm   string    `json:"-"`
+	Artist     string    `json:"artist"`
+	Album      string    `json:"album"`
+	Path       string    `json:"path"`
+	TrackCount int       `json:"trackCount"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	ArtistNorm string    `json:"-"`
+	AlbumNorm  string    `json:"-"`
 }
 
 // Store wraps the sqlite database.
@@ -121,12 +146,63 @@ func (s *Store) bootstrap() error {
 			album_norm TEXT NOT NULL,
 			PRIMARY KEY (artist_norm, album_norm)
 		);`,
+		`CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);`,
 	}
 	for _, q := range schemas {
 		if _, err := s.db.Exec(q); err != nil {
 			return fmt.Errorf("bootstrap schema: %w", err)
 		}
 	}
+	// Columns added after the first release; existing databases are migrated in place.
+	columns := []struct{ table, name, def string }{
+		{"job_items", "checkpoint", "TEXT NOT NULL DEFAULT ''"},
+		{"job_items", "source_url", "TEXT NOT NULL DEFAULT ''"},
+		{"jobs", "attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"jobs", "max_attempts", "INTEGER NOT NULL DEFAULT 1"},
+		{"jobs", "next_retry_at", "TEXT"},
+		{"jobs", "priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"jobs", "started_at", "TEXT"},
+		{"jobs", "run_at", "TEXT"},
+		{"jobs", "error_code", "TEXT NOT NULL DEFAULT ''"},
+		{"job_items", "target_path", "TEXT NOT NULL DEFAULT ''"},
+		{"job_items", "conflict", "TEXT NOT NULL DEFAULT ''"},
+		{"job_items", "estimated_size", "INTEGER NOT NULL DEFAULT 0"},
+		{"job_items", "skipped", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := s.ensureColumn(c.table, c.name, c.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumn adds a column to table unless it already exists.
+func (s *Store) ensureColumn(table, column, def string) error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	if _, err := s.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -141,8 +217,12 @@ func (s *Store) InsertJob(job *Job) error {
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`INSERT INTO jobs (id, status, phase, message, progress, artist, album, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		job.ID, job.Status, job.Phase, job.Message, job.Progress, job.Artist, job.Album, job.CreatedAt.Format(time.RFC3339Nano), job.UpdatedAt.Format(time.RFC3339Nano))
+	var runAt sql.NullString
+	if job.RunAt != nil {
+		runAt = sql.NullString{String: job.RunAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+	_, err = tx.Exec(`INSERT INTO jobs (id, status, phase, message, progress, artist, album, created_at, updated_at, max_attempts, priority, run_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Status, job.Phase, job.Message, job.Progress, job.Artist, job.Album, job.CreatedAt.Format(time.RFC3339Nano), job.UpdatedAt.Format(time.RFC3339Nano), job.MaxAttempts, job.Priority, runAt)
 	if err != nil {
 		return fmt.Errorf("insert job: %w", err)
 	}
@@ -177,6 +257,131 @@ func (s *Store) UpdateJobState(id, status, phase, message string, progress float
 	return nil
 }
 
+// UpdateJobStateIf moves a job to status/phase/message only while its status is
+// one of from. It reports whether the job was updated.
+func (s *Store) UpdateJobStateIf(id string, from []string, status, phase, message string) (bool, error) {
+	if len(from) == 0 {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	args := []any{status, phase, message, now.Format(time.RFC3339Nano), id}
+	for _, f := range from {
+		args = append(args, f)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(from)), ",")
+	res, err := s.db.Exec(`UPDATE jobs SET status=?, phase=?, message=?, updated_at=? WHERE id=? AND status IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return false, fmt.Errorf("update job state: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// UpdateJobItemCheckpoint records the last phase an item completed and, when
+// known, its resolved source URL.
+func (s *Store) UpdateJobItemCheckpoint(jobID, sourceID, checkpoint, sourceURL string) error {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`UPDATE job_items SET checkpoint=?, source_url=?, updated_at=? WHERE job_id=? AND source_id=?`,
+		checkpoint, sourceURL, now.Format(time.RFC3339Nano), jobID, sourceID); err != nil {
+		return fmt.Errorf("update job item checkpoint: %w", err)
+	}
+	return nil
+}
+
+// UpdateJobItemSkipped records whether placing found the item's album folder
+// already in place.
+func (s *Store) UpdateJobItemSkipped(jobID, sourceID string, skipped bool) error {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`UPDATE job_items SET skipped=?, updated_at=? WHERE job_id=? AND source_id=?`,
+		skipped, now.Format(time.RFC3339Nano), jobID, sourceID); err != nil {
+		return fmt.Errorf("update job item skipped: %w", err)
+	}
+	return nil
+}
+
+// UpdateJobItemPlan stores the dry-run outcome of an item: its resolved
+// source URL, target path, conflict, estimated size and message.
+func (s *Store) UpdateJobItemPlan(jobID string, item *JobItem) error {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`UPDATE job_items SET source_url=?, target_path=?, conflict=?, estimated_size=?, message=?, updated_at=? WHERE job_id=? AND source_id=?`,
+		item.SourceURL, item.TargetPath, item.Conflict, item.EstimatedSize, item.Message, now.Format(time.RFC3339Nano), jobID, item.SourceID); err != nil {
+		return fmt.Errorf("update job item plan: %w", err)
+	}
+	return nil
+}
+
+// RemoveJobItems deletes the given items of a job and renames the job to
+// artist and album in a single transaction.
+func (s *Store) RemoveJobItems(jobID string, sourceIDs []string, artist, album string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, sourceID := range sourceIDs {
+		if _, err := tx.Exec(`DELETE FROM job_items WHERE job_id=? AND source_id=?`, jobID, sourceID); err != nil {
+			return fmt.Errorf("remove job item: %w", err)
+		}
+	}
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE jobs SET artist=?, album=?, updated_at=? WHERE id=?`, artist, album, now.Format(time.RFC3339Nano), jobID); err != nil {
+		return fmt.Errorf("rename job: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UpdateJobAttempts records the failed-run counter and when the next automatic
+// retry is due; a nil nextRetryAt clears it.
+func (s *Store) UpdateJobAttempts(id string, attempts int, nextRetryAt *time.Time) error {
+	now := time.Now().UTC()
+	var next sql.NullString
+	if nextRetryAt != nil {
+		next = sql.NullString{String: nextRetryAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+	if _, err := s.db.Exec(`UPDATE jobs SET attempts=?, next_retry_at=?, updated_at=? WHERE id=?`,
+		attempts, next, now.Format(time.RFC3339Nano), id); err != nil {
+		return fmt.Errorf("update job attempts: %w", err)
+	}
+	return nil
+}
+
+// UpdateJobErrorCode records the error code of the job's last failure; an
+// empty code clears it.
+func (s *Store) UpdateJobErrorCode(id, code string) error {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`UPDATE jobs SET error_code=?, updated_at=? WHERE id=?`, code, now.Format(time.RFC3339Nano), id); err != nil {
+		return fmt.Errorf("update job error code: %w", err)
+	}
+	return nil
+}
+
+// RequeueJob moves a failed, partial or interrupted job back to queued with a
+// fresh attempt counter; its unfinished items are queued again. It reports
+// false when the job was not in a retryable state.
+func (s *Store) RequeueJob(id, message string) (bool, error) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`UPDATE jobs SET status='queued', phase='queued', message=?, attempts=0, next_retry_at=NULL, error_code='', finished_at=NULL, updated_at=?
+		WHERE id=? AND status IN ('failed', 'partial', 'interrupted')`, message, now, id)
+	if err != nil {
+		return false, fmt.Errorf("requeue job: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE job_items SET status='queued', message='queued', updated_at=? WHERE job_id=? AND status != 'completed'`, now, id); err != nil {
+		return false, fmt.Errorf("requeue job items: %w", err)
+	}
+	return true, tx.Commit()
+}
+
 // AddJobLog appends a log line for a job.
 func (s *Store) AddJobLog(jobID, message string) error {
 	now := time.Now().UTC()
@@ -194,48 +399,227 @@ func (s *Store) UpdateJobItem(jobID, sourceID, status, message string) error {
 	return nil
 }
 
+const jobColumns = `id, status, phase, message, progress, artist, album, created_at, updated_at, finished_at, attempts, max_attempts, next_retry_at, priority, started_at, run_at, error_code`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var createdAt, updatedAt, finishedAt, nextRetryAt, startedAt, runAt sql.NullString
+	if err := row.Scan(&job.ID, &job.Status, &job.Phase, &job.Message, &job.Progress, &job.Artist, &job.Album, &createdAt, &updatedAt, &finishedAt,
+		&job.Attempts, &job.MaxAttempts, &nextRetryAt, &job.Priority, &startedAt, &runAt, &job.ErrorCode); err != nil {
+		return nil, err
+	}
+	job.CreatedAt = parseTime(createdAt)
+	job.UpdatedAt = parseTime(updatedAt)
+	if finishedAt.Valid {
+		t := parseTime(finishedAt)
+		job.FinishedAt = &t
+	}
+	if nextRetryAt.Valid {
+		t := parseTime(nextRetryAt)
+		job.NextRetryAt = &t
+	}
+	if startedAt.Valid {
+		t := parseTime(startedAt)
+		job.StartedAt = &t
+	}
+	if runAt.Valid {
+		t := parseTime(runAt)
+		job.RunAt = &t
+	}
+	return &job, nil
+}
+
 // ListJobs returns latest jobs up to limit.
 func (s *Store) ListJobs(limit int) ([]Job, error) {
-	rows, err := s.db.Query(`SELECT id, status, phase, message, progress, artist, album, created_at, updated_at, finished_at FROM jobs ORDER BY datetime(created_at) DESC LIMIT ?`, limit)
+	return s.queryJobs(`SELECT `+jobColumns+` FROM jobs ORDER BY datetime(created_at) DESC LIMIT ?`, limit)
+}
+
+// queryJobs runs a SELECT of jobColumns and scans every row.
+func (s *Store) queryJobs(query string, args ...any) ([]Job, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var jobs []Job
 	for rows.Next() {
-		var job Job
-		var createdAt, updatedAt, finishedAt sql.NullString
-		if err := rows.Scan(&job.ID, &job.Status, &job.Phase, &job.Message, &job.Progress, &job.Artist, &job.Album, &createdAt, &updatedAt, &finishedAt); err != nil {
+		job, err := scanJob(rows)
+		if err != nil {
 			return nil, err
 		}
-		job.CreatedAt = parseTime(createdAt)
-		job.UpdatedAt = parseTime(updatedAt)
-		if finishedAt.Valid {
-			t := parseTime(finishedAt)
-			job.FinishedAt = &t
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// ListUnfinishedJobs returns queued and running jobs, oldest first, including items.
+func (s *Store) ListUnfinishedJobs() ([]Job, error) {
+	jobs, err := s.queryJobs(`SELECT ` + jobColumns + ` FROM jobs WHERE status IN ('queued', 'running') ORDER BY datetime(created_at) ASC`)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range jobs {
+		items, err := s.loadItems(jobs[idx].ID)
+		if err != nil {
+			return nil, err
 		}
-		jobs = append(jobs, job)
+		jobs[idx].Items = items
 	}
 	return jobs, nil
 }
 
+const (
+	// queueOrder is the dequeue order: highest priority first, then oldest.
+	queueOrder = `priority DESC, datetime(created_at) ASC, rowid ASC`
+	// waitingStatuses are the statuses of jobs that sit in the queue.
+	waitingStatuses = `'queued', 'scheduled', 'waiting_window', 'waiting_disk'`
+)
+
+// ClaimJob atomically moves the first job that is due (no pending retry or
+// run_at after now) and whose status is one of statuses to running, and
+// returns it with its items, or nil when none is waiting.
+func (s *Store) ClaimJob(now time.Time, statuses ...string) (*Job, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	ts := now.UTC().Format(time.RFC3339Nano)
+	args := []any{ts, ts}
+	for _, st := range statuses {
+		args = append(args, st)
+	}
+	args = append(args, ts, ts)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(statuses)), ",")
+	job, err := scanJob(s.db.QueryRow(`UPDATE jobs SET status='running', next_retry_at=NULL, started_at=?, updated_at=?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status IN (`+placeholders+`)
+				AND (next_retry_at IS NULL OR julianday(next_retry_at) <= julianday(?))
+				AND (run_at IS NULL OR julianday(run_at) <= julianday(?))
+			ORDER BY `+queueOrder+` LIMIT 1
+		)
+		RETURNING `+jobColumns, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("claim job: %w", err)
+	}
+	items, err := s.loadItems(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	job.Items = items
+	return job, nil
+}
+
+// ListQueuedJobs returns queued, scheduled and window-waiting jobs in dequeue
+// order, without items.
+func (s *Store) ListQueuedJobs() ([]Job, error) {
+	return s.queryJobs(`SELECT ` + jobColumns + ` FROM jobs WHERE status IN (` + waitingStatuses + `) ORDER BY ` + queueOrder)
+}
+
+// ListRunningJobs returns jobs currently claimed by a worker, without items.
+func (s *Store) ListRunningJobs() ([]Job, error) {
+	return s.queryJobs(`SELECT ` + jobColumns + ` FROM jobs WHERE status='running' ORDER BY datetime(started_at) ASC`)
+}
+
+// RecentJobDurations returns how long the last limit completed jobs ran,
+// newest first.
+func (s *Store) RecentJobDurations(limit int) ([]time.Duration, error) {
+	rows, err := s.db.Query(`SELECT started_at, finished_at FROM jobs
+		WHERE status='completed' AND started_at IS NOT NULL AND finished_at IS NOT NULL
+		ORDER BY datetime(finished_at) DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []time.Duration
+	for rows.Next() {
+		var startedAt, finishedAt string
+		if err := rows.Scan(&startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		if d := parseTimeString(finishedAt).Sub(parseTimeString(startedAt)); d > 0 {
+			out = append(out, d)
+		}
+	}
+	return out, rows.Err()
+}
+
+// UpdateJobPriority changes the priority of a job that has not finished yet:
+// waiting, paused, running or planned. It reports false when the job is
+// missing or already finished.
+func (s *Store) UpdateJobPriority(id string, priority int) (bool, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`UPDATE jobs SET priority=?, updated_at=? WHERE id=? AND status IN ('running', 'paused', 'planned', `+waitingStatuses+`)`,
+		priority, now.Format(time.RFC3339Nano), id)
+	if err != nil {
+		return false, fmt.Errorf("update job priority: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// CountQueuedJobs returns how many jobs are waiting to run, including those
+// waiting for a retry, their run_at or the download window.
+func (s *Store) CountQueuedJobs() (int, error) {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE status IN (` + waitingStatuses + `)`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count queued jobs: %w", err)
+	}
+	return n, nil
+}
+
+// ListActiveJobItems returns the items of jobs that are waiting, paused or
+// running, with JobID set. Items without their own artist carry the job's.
+func (s *Store) ListActiveJobItems() ([]JobItem, error) {
+	rows, err := s.db.Query(`SELECT i.job_id, i.source_id, i.title, COALESCE(NULLIF(i.artist, ''), j.artist, ''), i.album
+		FROM job_items i JOIN jobs j ON j.id = i.job_id
+		WHERE j.status IN ('running', 'paused', ` + waitingStatuses + `)
+		ORDER BY datetime(j.created_at), i.rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("list active job items: %w", err)
+	}
+	defer rows.Close()
+	var items []JobItem
+	for rows.Next() {
+		var it JobItem
+		if err := rows.Scan(&it.JobID, &it.SourceID, &it.Title, &it.Artist, &it.Album); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// GetJobStatus returns only the status of a job, or "" when it does not exist.
+func (s *Store) GetJobStatus(id string) (string, error) {
+	var status string
+	if err := s.db.QueryRow(`SELECT status FROM jobs WHERE id=?`, id).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return status, nil
+}
+
 // GetJob fetches a job by id including items and logs.
 func (s *Store) GetJob(id string) (*Job, error) {
-	row := s.db.QueryRow(`SELECT id, status, phase, message, progress, artist, album, created_at, updated_at, finished_at FROM jobs WHERE id=?`, id)
-	var job Job
-	var createdAt, updatedAt, finishedAt sql.NullString
-	if err := row.Scan(&job.ID, &job.Status, &job.Phase, &job.Message, &job.Progress, &job.Artist, &job.Album, &createdAt, &updatedAt, &finishedAt); err != nil {
+	job, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id=?`, id))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	job.CreatedAt = parseTime(createdAt)
-	job.UpdatedAt = parseTime(updatedAt)
-	if finishedAt.Valid {
-		t := parseTime(finishedAt)
-		job.FinishedAt = &t
-	}
 
 	items, err := s.loadItems(id)
 	if err != nil {
@@ -247,11 +631,13 @@ func (s *Store) GetJob(id string) (*Job, error) {
 	}
 	job.Items = items
 	job.Logs = logs
-	return &job, nil
+	return job, nil
 }
 
 func (s *Store) loadItems(jobID string) ([]JobItem, error) {
-	rows, err := s.db.Query(`SELECT job_id, source_id, source_type, title, artist, album, cover_url, status, message, created_at, updated_at FROM job_items WHERE job_id=?`, jobID)
+	rows, err := s.db.Query(`SELECT job_id, source_id, source_type, title, artist, album, cover_url, status, message, created_at, updated_at, checkpoint, source_url,
+		target_path, conflict, estimated_size, skipped
+		FROM job_items WHERE job_id=? ORDER BY rowid`, jobID)
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +646,8 @@ func (s *Store) loadItems(jobID string) ([]JobItem, error) {
 	for rows.Next() {
 		var it JobItem
 		var createdAt, updatedAt string
-		if err := rows.Scan(&it.JobID, &it.SourceID, &it.SourceType, &it.Title, &it.Artist, &it.Album, &it.CoverURL, &it.Status, &it.Message, &createdAt, &updatedAt); err != nil {
+		if err := rows.Scan(&it.JobID, &it.SourceID, &it.SourceType, &it.Title, &it.Artist, &it.Album, &it.CoverURL, &it.Status, &it.Message, &createdAt, &updatedAt, &it.Checkpoint, &it.SourceURL,
+			&it.TargetPath, &it.Conflict, &it.EstimatedSize, &it.Skipped); err != nil {
 			return nil, err
 		}
 		it.CreatedAt = parseTimeString(createdAt)
@@ -289,6 +676,26 @@ func (s *Store) loadLogs(jobID string) ([]JobLogLine, error) {
 	return logs, nil
 }
 
+// GetSetting returns the stored value for key and whether it was set.
+func (s *Store) GetSetting(key string) (string, bool, error) {
+	var value string
+	if err := s.db.QueryRow(`SELECT value FROM settings WHERE key=?`, key).Scan(&value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("get setting %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// SetSetting stores value under key, replacing any previous value.
+func (s *Store) SetSetting(key, value string) error {
+	if _, err := s.db.Exec(`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, key, value); err != nil {
+		return fmt.Errorf("set setting %s: %w", key, err)
+	}
+	return nil
+}
+
 // ReplaceLibraryIndex replaces the entire library_index table with the provided entries.
 func (s *Store) ReplaceLibraryIndex(entries []LibraryEntry) error {
 	tx, err := s.db.Begin()
@@ -332,17 +739,32 @@ func (s *Store) ListLibrary() ([]LibraryEntry, error) {
 	return out, nil
 }
 
-// LibraryExists reports whether a given normalized artist/album is in the index.
-func (s *Store) LibraryExists(artistNorm, albumNorm string) (bool, error) {
-	row := s.db.QueryRow(`SELECT 1 FROM library_index WHERE artist_norm=? AND album_norm=? LIMIT 1`, artistNorm, albumNorm)
-	var dummy int
-	if err := row.Scan(&dummy); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
+// ListLibraryByArtists returns the indexed albums for any of the normalized artists in one query.
+func (s *Store) ListLibraryByArtists(artistNorms []string) ([]LibraryEntry, error) {
+	if len(artistNorms) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(artistNorms)), ",")
+	args := make([]any, len(artistNorms))
+	for idx, a := range artistNorms {
+		args[idx] = a
+	}
+	rows, err := s.db.Query(`SELECT artist, album, path, track_count, updated_at, artist_norm, album_norm FROM library_index WHERE artist_norm IN (`+placeholders+`) ORDER BY artist_norm, album_norm`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LibraryEntry
+	for rows.Next() {
+		var e LibraryEntry
+		var updatedAt string
+		if err := rows.Scan(&e.Artist, &e.Album, &e.Path, &e.TrackCount, &updatedAt, &e.ArtistNorm, &e.AlbumNorm); err != nil {
+			return nil, err
 		}
-		return false, err
+		e.UpdatedAt = parseTimeString(updatedAt)
+		out = append(out, e)
 	}
-	return true, nil
+	return out, nil
 }
 
 func parseTime(ns sql.NullString) time.Time {
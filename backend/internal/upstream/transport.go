@@ -0,0 +1,327 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Options tune the shared outbound HTTP layer.
+type Options struct {
+	RatePerSecond    float64       // sustained requests per second per host
+	Burst            int           // token bucket size per host
+	MaxRetries       int           // retries after the first attempt
+	BaseBackoff      time.Duration // first retry delay before jitter
+	MaxBackoff       time.Duration // cap for backoff and Retry-After waits
+	BreakerThreshold int           // consecutive failures that open the breaker
+	BreakerCooldown  time.Duration // how long the breaker stays open
+}
+
+// Transport is an http.RoundTripper that rate limits, retries and circuit-breaks per host.
+// Every outbound client (search, resolver, downloader) shares one Transport.
+type Transport struct {
+	next http.RoundTripper
+	opts Options
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	limiter *tokenBucket
+	breaker *breaker
+}
+
+// New wraps next (http.DefaultTransport when nil).
+func New(opts Options, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if opts.RatePerSecond <= 0 {
+		opts.RatePerSecond = 2
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = 1
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = 30 * time.Second
+	}
+	return &Transport{next: next, opts: opts, hosts: map[string]*hostState{}}
+}
+
+// Client returns an http.Client using the transport. A zero timeout leaves
+// deadlines to the request context, which suits long downloads.
+func (t *Transport) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: t, Timeout: timeout}
+}
+
+// RoundTrip sends req, waiting for a rate-limit token and retrying transient failures.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.host(req.URL.Host)
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if !host.breaker.allow(time.Now()) {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+		}
+		if err := host.limiter.wait(ctx); err != nil {
+			host.breaker.release()
+			return nil, err
+		}
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			host.breaker.release()
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		retryable := false
+		var delay time.Duration
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// The caller gave up; that says nothing about the host.
+			host.breaker.release()
+		case err != nil:
+			host.breaker.failure(time.Now())
+			retryable = true
+		case resp.StatusCode == http.StatusTooManyRequests:
+			// Rate limited, not broken: back off without tripping the breaker,
+			// but without clearing earlier failures either.
+			host.breaker.release()
+			retryable = true
+			delay = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		case resp.StatusCode >= 500:
+			host.breaker.failure(time.Now())
+			retryable = true
+		default:
+			host.breaker.success()
+		}
+
+		if !retryable || attempt >= t.opts.MaxRetries || !replayable(req) {
+			return resp, err
+		}
+		if delay <= 0 {
+			delay = t.backoff(attempt)
+		}
+		if delay > t.opts.MaxBackoff {
+			delay = t.opts.MaxBackoff
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// BreakerStatus is the health view of one host's circuit breaker.
+type BreakerStatus struct {
+	State     string     `json:"state"` // closed|open|half_open
+	Failures  int        `json:"failures"`
+	OpenUntil *time.Time `json:"openUntil,omitempty"`
+}
+
+// Breakers reports breaker state for every host contacted so far.
+func (t *Transport) Breakers() map[string]BreakerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]BreakerStatus, len(t.hosts))
+	now := time.Now()
+	for name, h := range t.hosts {
+		out[name] = h.breaker.status(now)
+	}
+	return out
+}
+
+func (t *Transport) host(name string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &hostState{
+			limiter: newTokenBucket(t.opts.RatePerSecond, t.opts.Burst),
+			breaker: &breaker{threshold: t.opts.BreakerThreshold, cooldown: t.opts.BreakerCooldown},
+		}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// backoff returns an exponential delay with full jitter for the given attempt.
+func (t *Transport) backoff(attempt int) time.Duration {
+	ceiling := t.opts.BaseBackoff << attempt
+	if ceiling <= 0 || ceiling > t.opts.MaxBackoff {
+		ceiling = t.opts.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + time.Millisecond
+}
+
+// replayable reports whether req can be sent again safely.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a request whose body can be read again for retries.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("rewind request body: %w", err)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// breaker opens after threshold consecutive failures and lets a single probe
+// through once the cooldown has passed.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// release ends a probe that neither succeeded nor failed, keeping the failure count.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+func (b *breaker) status(now time.Time) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := BreakerStatus{State: breakerClosed, Failures: b.failures}
+	if b.failures >= b.threshold {
+		st.State = breakerHalfOpen
+		if now.Before(b.openUntil) {
+			st.State = breakerOpen
+			until := b.openUntil
+			st.OpenUntil = &until
+		}
+	}
+	return st
+}
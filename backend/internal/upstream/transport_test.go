@@ -0,0 +1,239 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers every request with the status returned by status
+// and counts the hits.
+func countingServer(t *testing.T, status func(hit int) int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status(n))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func get(t *testing.T, tr *Transport, target string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func hostOf(t *testing.T, target string) string {
+	t.Helper()
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+// fast keeps the limiter and backoff out of the way of tests about other behaviour.
+var fast = Options{RatePerSecond: 1000, Burst: 100, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+func TestTokenBucketPerHost(t *testing.T) {
+	ok := func(int) int { return http.StatusOK }
+	a, _ := countingServer(t, ok, nil)
+	b, _ := countingServer(t, ok, nil)
+	tr := New(Options{RatePerSecond: 20, Burst: 2}, nil)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := get(t, tr, a.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst covers two requests; the other two wait about 50ms each.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("4 requests at 20/s with burst 2 took %s", elapsed)
+	}
+
+	// Another host has a bucket of its own.
+	start = time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, tr, b.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("second host waited %s for tokens", elapsed)
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	srv, hits := countingServer(t, func(int) int { return http.StatusBadGateway }, nil)
+	opts := fast
+	opts.MaxRetries = 2
+	resp, err := get(t, New(opts, nil), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("resp = %v, %v", resp, err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("hits = %d, want the first attempt and 2 retries", got)
+	}
+
+	// A later success ends the retries.
+	srv, hits = countingServer(t, func(hit int) int {
+		if hit == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}, nil)
+	resp, err = get(t, New(opts, nil), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("resp = %v, %v after %d hits", resp, err, hits.Load())
+	}
+}
+
+func TestBackoffIsJitteredAndCapped(t *testing.T) {
+	tr := New(Options{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}, nil)
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := min(10*time.Millisecond<<attempt, 50*time.Millisecond)
+		for i := 0; i < 50; i++ {
+			if d := tr.backoff(attempt); d <= 0 || d > ceiling+time.Millisecond {
+				t.Fatalf("backoff(%d) = %s, want within (0, %s]", attempt, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestRetryAfterIsCappedAtMaxBackoff(t *testing.T) {
+	srv, hits := countingServer(t, func(hit int) int {
+		if hit == 1 {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	}, http.Header{"Retry-After": {"30"}})
+	opts := fast
+	opts.MaxRetries = 1
+	opts.MaxBackoff = 20 * time.Millisecond
+
+	start := time.Now()
+	resp, err := get(t, New(opts, nil), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Fatalf("resp = %v, %v after %d hits", resp, err, hits.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %s, want MaxBackoff instead of Retry-After", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 May 2024 12:00:10 GMT": 10 * time.Second,
+	}
+	for value, want := range cases {
+		if got := retryAfter(value, now); got != want {
+			t.Errorf("retryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	srv, hits := countingServer(t, func(int) int {
+		if failing.Load() {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}, nil)
+	opts := fast
+	opts.BreakerThreshold = 2
+	opts.BreakerCooldown = 50 * time.Millisecond
+	tr := New(opts, nil)
+	host := hostOf(t, srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, tr, srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := tr.Breakers()[host]; st.State != breakerOpen || st.Failures != 2 || st.OpenUntil == nil {
+		t.Fatalf("after 2 failures: %+v", st)
+	}
+	if _, err := get(t, tr, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker let a request through: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, the open breaker should not contact the host", hits.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if st := tr.Breakers()[host]; st.State != breakerHalfOpen {
+		t.Fatalf("after the cooldown: %+v", st)
+	}
+	// A failed probe opens the breaker again.
+	if _, err := get(t, tr, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if st := tr.Breakers()[host]; st.State != breakerOpen {
+		t.Fatalf("after a failed probe: %+v", st)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	failing.Store(false)
+	resp, err := get(t, tr, srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("probe = %v, %v", resp, err)
+	}
+	if st := tr.Breakers()[host]; st.State != breakerClosed || st.Failures != 0 {
+		t.Errorf("after a successful probe: %+v", st)
+	}
+}
+
+func TestTooManyRequestsKeepsFailureCount(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv, _ := countingServer(t, func(int) int { return int(status.Load()) }, nil)
+	opts := fast
+	opts.BreakerThreshold = 3
+	tr := New(opts, nil)
+	host := hostOf(t, srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, tr, srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status.Store(http.StatusTooManyRequests)
+	if _, err := get(t, tr, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if st := tr.Breakers()[host]; st.State != breakerClosed || st.Failures != 2 {
+		t.Fatalf("after a 429: %+v, want the 2 failures kept", st)
+	}
+	status.Store(http.StatusInternalServerError)
+	if _, err := get(t, tr, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if st := tr.Breakers()[host]; st.State != breakerOpen {
+		t.Errorf("third failure around a 429: %+v, want open", st)
+	}
+}
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrFreeSpaceUnsupported is returned by FreeSpace where free space cannot be read.
+var ErrFreeSpaceUnsupported = errors.New("free space not available on this platform")
+
+// FormatBytes renders n with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
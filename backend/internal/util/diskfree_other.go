@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd
+
+package util
+
+// FreeSpace reports ErrFreeSpaceUnsupported on platforms without statfs.
+func FreeSpace(path string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
+
+// DeviceID reports ErrFreeSpaceUnsupported on platforms without statfs.
+func DeviceID(path string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
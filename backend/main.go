@@ -8,33 +8,77 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"navidrome-helper/internal/config"
 	"navidrome-helper/internal/jobs"
 	"navidrome-helper/internal/library"
+	"navidrome-helper/internal/search"
 	"navidrome-helper/internal/server"
+	"navidrome-helper/internal/source"
 	"navidrome-helper/internal/store"
+	"navidrome-helper/internal/upstream"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
 	dbPath := filepath.Join(cfg.DataDir, "navidrome-helper.db")
 	store, err := store.New(dbPath)
 	if err != nil {
 		log.Fatalf("init store: %v", err)
 	}
 
-	runner := jobs.NewRunner(store, cfg)
+	outbound := upstream.New(upstream.Options{
+		RatePerSecond:    cfg.UpstreamRate,
+		Burst:            cfg.UpstreamBurst,
+		MaxRetries:       cfg.UpstreamMaxRetries,
+		BreakerThreshold: cfg.UpstreamBreakerThreshold,
+		BreakerCooldown:  cfg.UpstreamBreakerCooldown,
+	}, nil)
+
+	var resolver source.Resolver = source.StubResolver{Delay: 300 * time.Millisecond}
+	var downloader source.Downloader = source.StubDownloader{Delay: 300 * time.Millisecond}
+	if cfg.EnableDownloads {
+		resolver = source.NewDoubleDoubleResolver(cfg.ResolverBaseURL, outbound.Client(30*time.Second))
+		downloader = source.NewPixeldrainDownloader(outbound.Client(0))
+	}
+
+	indexer := library.NewIndexer(cfg, store)
+	runner := jobs.NewRunner(store, cfg, jobs.NewPipeline(cfg, resolver, downloader, indexer))
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	runner.Start(ctx)
 
-	indexer := library.NewIndexer(cfg, store)
 	if _, err := indexer.Refresh(ctx); err != nil {
 		log.Printf("library refresh at start failed: %v", err)
 	}
 
-	srv := server.New(cfg, store, runner, indexer)
+	httpClient := outbound.Client(15 * time.Second)
+	var providers []search.SearchProvider
+	if cfg.AmazonAPIBaseURL != "" {
+		providers = append(providers, search.NewAmazonProvider(cfg.AmazonAPIBaseURL, httpClient))
+	}
+	for _, pc := range cfg.SearchProviders {
+		providers = append(providers, search.NewCompatibleProvider(pc.Name, pc.BaseURL, httpClient))
+	}
+	var provider search.SearchProvider
+	switch len(providers) {
+	case 0:
+		provider = search.NewMockProvider()
+	case 1:
+		provider = providers[0]
+	default:
+		provider = search.NewFederated(providers, cfg.SearchProviderTimeout)
+	}
+	log.Printf("search provider: %s", provider.Name())
+	if cfg.SearchCacheTTL > 0 {
+		provider = search.NewCache(provider, cfg.SearchCacheTTL)
+	}
+
+	srv := server.New(cfg, store, runner, indexer, provider, outbound)
 	go func() {
 		log.Printf("backend listening on :%s", cfg.Port)
 		if err := http.ListenAndServe(":"+cfg.Port, srv.Routes()); err != nil && err != http.ErrServerClosed {
@@ -48,4 +92,5 @@ func main() {
 	<-sigs
 	log.Println("shutting down...")
 	cancel()
+	runner.Wait()
 }
@@ -1,9 +1,14 @@
 import { useEffect, useMemo, useState } from 'react'
 import './App.css'
-import { createImport, getJob, getLibrary, listJobs, refreshLibrary, search } from './api'
+import { cancelJob, confirmJob, createImport, getJob, getLibrary, listJobs, pauseJob, planImport, refreshLibrary, resumeJob, retryJob, search, setJobPriority } from './api'
 import type { ImportRequestItem, Job, LibraryEntry, SearchItem } from './types'
 
 const MIN_QUERY = 2
+const FINISHED_STATUSES = ['completed', 'partial', 'failed', 'interrupted', 'cancelled']
+const RETRYABLE_STATUSES = ['failed', 'partial', 'interrupted']
+const PAUSABLE_STATUSES = ['queued', 'scheduled', 'waiting_window', 'waiting_disk', 'running']
+// Jobs still waiting to start; PATCH /api/jobs/{id} accepts a priority for these.
+const PRIORITIZABLE_STATUSES = ['queued', 'scheduled', 'waiting_window', 'waiting_disk', 'paused', 'planned']
 
 function App() {
   const [query, setQuery] = useState('')
@@ -15,6 +20,7 @@ function App() {
 
   const [jobId, setJobId] = useState('')
   const [activeJob, setActiveJob] = useState<Job | null>(null)
+  const [pollToken, setPollToken] = useState(0)
   const [recentJobs, setRecentJobs] = useState<Job[]>([])
   const [library, setLibrary] = useState<LibraryEntry[]>([])
   const [libraryLoading, setLibraryLoading] = useState(false)
@@ -56,7 +62,7 @@ function App() {
       try {
         const job = await getJob(jobId)
         setActiveJob(job)
-        if (job.status === 'completed' || job.status === 'failed') {
+        if (FINISHED_STATUSES.includes(job.status)) {
           stop = true
           listJobs().then((res) => setRecentJobs(res.jobs ?? []))
           return
@@ -71,7 +77,7 @@ function App() {
       poll()
     }, 1500)
     return () => clearInterval(interval)
-  }, [jobId])
+  }, [jobId, pollToken])
 
   const normalizedSelection = useMemo(() => {
     const next: Record<string, SearchItem> = {}
@@ -95,7 +101,8 @@ function App() {
     })
   }
 
-  const startImport = async () => {
+  // A dry run creates a planned job; it is imported from the job panel with "Confirm import".
+  const startImport = async (dryRun = false) => {
     const items: ImportRequestItem[] = Object.values(normalizedSelection).map((i) => ({
       id: i.id,
       type: i.type,
@@ -107,7 +114,7 @@ function App() {
     }))
     setError('')
     try {
-      const res = await createImport(items)
+      const res = await (dryRun ? planImport(items) : createImport(items))
       setJobId(res.jobId)
       setShowConfirm(false)
       setSelected({})
@@ -167,7 +174,9 @@ function App() {
           {results.map((item) => {
             const normalized = normalizeSelection(item)
             const selectedState = normalizedSelection[normalized.id]
-            const already = item.exists
+            const match = item.match
+            const already = match?.status === 'exact'
+            const incomplete = match?.status === 'incomplete'
             return (
               <article
                 key={item.id}
@@ -183,6 +192,14 @@ function App() {
                     <p className="muted small">Album: {item.albumTitle}</p>
                   )}
                   {already && <p className="exists">Already in library</p>}
+                  {incomplete && (
+                    <p className="exists">
+                      Complete this album ({match?.localTracks}/{item.tracks} tracks in library)
+                    </p>
+                  )}
+                  {match?.status === 'fuzzy' && (
+                    <p className="exists">Similar album in library: {match.album}</p>
+                  )}
                   <p className="muted small">
                     {item.tracks ? `${item.tracks} tracks` : 'single'} ·{' '}
                     {item.duration ? formatDuration(item.duration) : 'duration n/a'}
@@ -268,7 +285,78 @@ function App() {
             </div>
             <div className="muted small">
               Phase: {activeJob.phase} · {activeJob.message}
+              {activeJob.attempts > 0 && ` · attempt ${activeJob.attempts} of ${activeJob.maxAttempts}`}
             </div>
+            {activeJob.items && activeJob.items.length > 1 && (
+              <div className="logs">
+                {activeJob.items.map((item) => (
+                  <div key={item.sourceId} className="tiny muted">
+                    {item.status} — {item.album || item.title} · {item.message}
+                  </div>
+                ))}
+              </div>
+            )}
+            {!FINISHED_STATUSES.includes(activeJob.status) && (
+              <button
+                className="ghost"
+                onClick={() =>
+                  cancelJob(activeJob.id)
+                    .then(() => getJob(activeJob.id))
+                    .then(setActiveJob)
+                    .catch((err) => setError(err.message || 'Cancel failed'))
+                }
+              >
+                Cancel job
+              </button>
+            )}
+            {PAUSABLE_STATUSES.includes(activeJob.status) && (
+              <button
+                className="ghost"
+                onClick={() =>
+                  pauseJob(activeJob.id)
+                    .then(setActiveJob)
+                    .catch((err) => setError(err.message || 'Pause failed'))
+                }
+              >
+                Pause job
+              </button>
+            )}
+            {activeJob.status === 'paused' && (
+              <button
+                className="ghost"
+                onClick={() =>
+                  resumeJob(activeJob.id)
+                    .then(setActiveJob)
+                    .catch((err) => setError(err.message || 'Resume failed'))
+                }
+              >
+                Resume job
+              </button>
+            )}
+            {activeJob.status === 'planned' && (
+              <button
+                className="ghost"
+                onClick={() =>
+                  confirmJob(activeJob.id)
+                    .then(setActiveJob)
+                    .catch((err) => setError(err.message || 'Confirm failed'))
+                }
+              >
+                Confirm import
+              </button>
+            )}
+            {RETRYABLE_STATUSES.includes(activeJob.status) && (
+              <button
+                className="ghost"
+                onClick={() =>
+                  retryJob(activeJob.id)
+                    .then(() => setPollToken((n) => n + 1))
+                    .catch((err) => setError(err.message || 'Retry failed'))
+                }
+              >
+                Retry job
+              </button>
+            )}
             {activeJob.logs && activeJob.logs.length > 0 && (
               <div className="logs">
                 {activeJob.logs.slice(-4).map((log) => (
@@ -302,6 +390,19 @@ function App() {
               <div className="label">{job.album || 'Unknown album'}</div>
               <div className="muted small">{job.artist}</div>
               <div className="muted tiny">{new Date(job.createdAt).toLocaleString()}</div>
+              {PRIORITIZABLE_STATUSES.includes(job.status) && (
+                <button
+                  className="ghost"
+                  onClick={() =>
+                    setJobPriority(job.id, job.priority + 10)
+                      .then(() => listJobs())
+                      .then((res) => setRecentJobs(res.jobs ?? []))
+                      .catch((err) => setError(err.message || 'Failed to prioritize job'))
+                  }
+                >
+                  Prioritize
+                </button>
+              )}
             </div>
           ))}
         </div>
@@ -349,7 +450,10 @@ function App() {
               <button className="ghost" onClick={() => setShowConfirm(false)}>
                 Cancel
               </button>
-              <button className="primary" onClick={startImport}>
+              <button className="ghost" onClick={() => startImport(true)}>
+                Dry run first
+              </button>
+              <button className="primary" onClick={() => startImport()}>
                 Yes, start import
               </button>
             </div>
@@ -367,7 +471,7 @@ function normalizeSelection(item: SearchItem): SearchItem {
       id: item.albumId,
       type: 'album',
       title: item.albumTitle || item.title,
-      exists: item.exists,
+      match: item.match,
     }
   }
   return item
@@ -1,4 +1,4 @@
-import type { SearchItem, ImportRequestItem, Job, JobListResponse, LibraryResponse } from './types'
+import type { AlbumDetail, Discography, SearchItem, SearchResponse, ImportDuplicate, ImportPlan, ImportRequestItem, Job, JobListResponse, LibraryMatchQuery, LibraryMatchResult, LibraryResponse, QueueView } from './types'
 
 const API_BASE = import.meta.env.VITE_API_BASE ?? ''
 
@@ -16,21 +16,76 @@ async function request<T>(path: string, options: RequestInit = {}): Promise<T> {
 }
 
 export async function search(query: string): Promise<SearchItem[]> {
-  const data = await request<{ items: SearchItem[] }>(`/api/search?q=${encodeURIComponent(query)}`)
+  const data = await request<SearchResponse>(`/api/search?q=${encodeURIComponent(query)}`)
   return data.items ?? []
 }
 
-export async function createImport(items: ImportRequestItem[]): Promise<{ jobId: string }> {
+export async function getAlbum(id: string): Promise<AlbumDetail> {
+  return request<AlbumDetail>(`/api/albums/${encodeURIComponent(id)}`)
+}
+
+export async function getArtistAlbums(id: string): Promise<Discography> {
+  return request<Discography>(`/api/artists/${encodeURIComponent(id)}/albums`)
+}
+
+export interface ImportOptions {
+  priority?: number
+  runAt?: string
+}
+
+export async function createImport(items: ImportRequestItem[], options: ImportOptions = {}): Promise<{ jobId: string; duplicates?: ImportDuplicate[] }> {
   return request('/api/import', {
     method: 'POST',
-    body: JSON.stringify({ items }),
+    body: JSON.stringify({ items, ...options }),
   })
 }
 
+export async function planImport(items: ImportRequestItem[], options: ImportOptions = {}): Promise<{ jobId: string; dryRun: true; plan: ImportPlan }> {
+  return request('/api/import', {
+    method: 'POST',
+    body: JSON.stringify({ items, ...options, dryRun: true }),
+  })
+}
+
+export async function confirmJob(id: string): Promise<Job> {
+  return request<Job>(`/api/jobs/${id}/confirm`, { method: 'POST' })
+}
+
 export async function getJob(id: string): Promise<Job> {
   return request<Job>(`/api/jobs/${id}`)
 }
 
+export async function cancelJob(id: string): Promise<{ jobId: string; status: string }> {
+  return request(`/api/jobs/${id}/cancel`, { method: 'POST' })
+}
+
+export async function retryJob(id: string): Promise<{ jobId: string; status: string }> {
+  return request(`/api/jobs/${id}/retry`, { method: 'POST' })
+}
+
+export async function pauseJob(id: string): Promise<Job> {
+  return request<Job>(`/api/jobs/${id}/pause`, { method: 'POST' })
+}
+
+export async function resumeJob(id: string): Promise<Job> {
+  return request<Job>(`/api/jobs/${id}/resume`, { method: 'POST' })
+}
+
+export async function setQueuePaused(paused: boolean): Promise<{ paused: boolean }> {
+  return request(`/api/queue/${paused ? 'pause' : 'resume'}`, { method: 'POST' })
+}
+
+export async function setJobPriority(id: string, priority: number): Promise<Job> {
+  return request<Job>(`/api/jobs/${id}`, {
+    method: 'PATCH',
+    body: JSON.stringify({ priority }),
+  })
+}
+
+export async function getQueue(): Promise<QueueView> {
+  return request<QueueView>('/api/queue')
+}
+
 export async function listJobs(): Promise<JobListResponse> {
   return request<JobListResponse>('/api/jobs')
 }
@@ -43,3 +98,11 @@ export async function getLibrary(refresh = false): Promise<LibraryResponse> {
 export async function refreshLibrary(): Promise<LibraryResponse> {
   return request<LibraryResponse>('/api/library/refresh', { method: 'POST' })
 }
+
+export async function matchLibrary(items: LibraryMatchQuery[]): Promise<LibraryMatchResult[]> {
+  const data = await request<{ matches: LibraryMatchResult[] }>('/api/library/match', {
+    method: 'POST',
+    body: JSON.stringify({ items }),
+  })
+  return data.matches ?? []
+}
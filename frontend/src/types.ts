@@ -1,16 +1,75 @@
 export type SearchItemType = 'album' | 'song'
 
+export type MatchStatus = 'exact' | 'incomplete' | 'fuzzy' | 'none'
+
+export interface LibraryMatch {
+  status: MatchStatus
+  path?: string
+  album?: string
+  localTracks: number
+}
+
 export interface SearchItem {
   id: string
   type: SearchItemType
   title: string
   artist: string
+  artistId?: string
   albumId?: string
   albumTitle?: string
   coverUrl: string
   tracks?: number
   duration?: number
-  exists?: boolean
+  year?: number
+  sources?: string[]
+  rank?: number
+  match?: LibraryMatch
+}
+
+export interface SearchResponse {
+  items: SearchItem[]
+  total: number
+  totalApproximate?: boolean
+  nextCursor?: string
+  degraded?: { provider: string; error: string }[]
+}
+
+export interface AlbumTrack {
+  id: string
+  title: string
+  number: number
+  disc: number
+  duration: number
+  explicit: boolean
+}
+
+export interface AlbumDetail {
+  id: string
+  title: string
+  artist: string
+  artistId?: string
+  coverUrl: string
+  releaseDate?: string
+  label?: string
+  explicit: boolean
+  duration?: number
+  tracks: AlbumTrack[]
+  source?: string
+  match: LibraryMatch
+}
+
+export type LibraryStatus = 'present' | 'partial' | 'missing'
+
+export interface DiscographyAlbum extends SearchItem {
+  status: LibraryStatus
+  match: LibraryMatch
+}
+
+export interface Discography {
+  artistId: string
+  artist: string
+  albums: DiscographyAlbum[]
+  summary: { present: number; partial: number; missing: number }
 }
 
 export interface ImportRequestItem {
@@ -32,6 +91,11 @@ export interface JobItem {
   coverUrl: string
   status: string
   message: string
+  checkpoint?: string
+  sourceUrl?: string
+  targetPath?: string
+  conflict?: 'exists' | 'queued' | 'duplicate'
+  estimatedSize?: number
 }
 
 export interface JobLog {
@@ -51,6 +115,13 @@ export interface Job {
   createdAt: string
   updatedAt?: string
   finishedAt?: string
+  startedAt?: string
+  priority: number
+  runAt?: string
+  attempts: number
+  maxAttempts: number
+  nextRetryAt?: string
+  errorCode?: string
   items?: JobItem[]
   logs?: JobLog[]
 }
@@ -59,6 +130,30 @@ export interface JobListResponse {
   jobs: Job[]
 }
 
+export interface QueueEntry extends Job {
+  position: number
+  estimatedStart: string
+}
+
+export interface QueueView {
+  paused: boolean
+  running: Job[]
+  queued: QueueEntry[]
+  averageDurationSeconds: number
+}
+
+export interface ImportDuplicate {
+  sourceId: string
+  jobId: string
+}
+
+export interface ImportPlan {
+  items: JobItem[]
+  estimatedSize: number
+  conflicts: number
+  unresolved: number
+}
+
 export interface LibraryEntry {
   artist: string
   album: string
@@ -67,6 +162,16 @@ export interface LibraryEntry {
   updatedAt: string
 }
 
+export interface LibraryMatchQuery {
+  artist: string
+  album: string
+  tracks?: number
+}
+
+export interface LibraryMatchResult extends LibraryMatchQuery {
+  match: LibraryMatch
+}
+
 export interface LibraryResponse {
   library: LibraryEntry[]
 }